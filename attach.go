@@ -0,0 +1,261 @@
+package gomcserver
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/shirou/gopsutil/v3/process"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// stoppingPattern matches the line printed when the server begins a clean shutdown.
+var stoppingPattern = regexp.MustCompile(`^Stopping (?:the )?server`)
+
+// ErrNoRuntimeState is returned by Attach when no running server was recorded
+// for the directory.
+var ErrNoRuntimeState = errors.New("no runtime state found for server")
+
+const attachPollInterval = time.Second
+
+// Attach reconnects to a server process that was started by an earlier
+// controller and is still running. The process is identified from the runtime
+// state file under .mcserverlib/ and verified to still be the same server
+// JVM. Output is followed by tailing logs/latest.log. Commands go through the
+// console FIFO of a detached server, or over RCON if it was enabled when the
+// server was started.
+//
+// The exit code of an attached process cannot be observed; ExitInfo reports a
+// clean exit if the server logged its shutdown and a crash otherwise.
+func (s *Server) Attach() error {
+	if err := s.beginStart(); err != nil {
+		return err
+	}
+	if err := s.attach(); err != nil {
+		s.failStart(StateStopped)
+		return err
+	}
+	return nil
+}
+
+func (s *Server) attach() error {
+	state, err := s.readRuntimeState()
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNoRuntimeState
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := verifyProcess(state, s.Directory); err != nil {
+		s.removeRuntimeState()
+		return err
+	}
+	proc, err := os.FindProcess(state.PID)
+	if err != nil {
+		return fmt.Errorf("failed to find process %d: %w", state.PID, err)
+	}
+	if err := s.acquireLock(); err != nil {
+		return err
+	}
+
+	logPath := s.latestLogPath()
+	offset, ready, players := s.scanExistingLog(logPath)
+
+	// A console pipe dies with the controller that created it, so commands go
+	// through the console FIFO of a detached server or fall back to RCON.
+	var fifo *os.File
+	if state.ConsoleFifo != "" && state.Transport == TransportStdin {
+		if f, err := openConsoleFifo(state.ConsoleFifo); err == nil {
+			fifo = f
+		}
+	}
+	s.mu.Lock()
+	done := s.done
+	s.cmdline = state.Cmdline
+	s.stdinPipe = nil
+	s.consoleFifo = nil
+	s.detached = state.Detached
+	s.rconAddr = state.RconAddr
+	s.rconPassword = state.RconPassword
+	s.transport = TransportStdin
+	if fifo != nil {
+		s.consoleFifo = fifo
+		s.stdinPipe = fifo
+	} else if s.rconAddr != "" {
+		s.transport = TransportRcon
+	}
+	s.process = &attachedProcess{proc: proc}
+	s.pid = state.PID
+	s.startedAt = state.StartedAt
+	s.stopRequested = false
+	s.sawStopping = false
+	s.players = players
+	s.running = true
+	s.mu.Unlock()
+
+	s.setState(StateStarting)
+	if ready {
+		s.setState(StateReady)
+	}
+
+	s.startTail(logPath, offset)
+	go s.watchAttached(state, done)
+	return nil
+}
+
+// verifyProcess checks that the recorded PID still belongs to the server JVM
+// started in directory, guarding against PID reuse.
+func verifyProcess(state *runtimeState, directory string) error {
+	if state.PID <= 0 {
+		return errors.New("runtime state has no PID")
+	}
+	p, err := process.NewProcess(int32(state.PID))
+	if err != nil {
+		return fmt.Errorf("server process %d is no longer running: %w", state.PID, err)
+	}
+	if state.CreateTime != 0 {
+		if createTime, err := p.CreateTime(); err == nil && createTime != state.CreateTime {
+			return fmt.Errorf("process %d was started at a different time and is not the server", state.PID)
+		}
+	}
+	cmdline, err := p.CmdlineSlice()
+	if err != nil {
+		return fmt.Errorf("failed to read command line of process %d: %w", state.PID, err)
+	}
+	target := launchTarget(state.Cmdline)
+	if !containsArg(cmdline, target) {
+		return fmt.Errorf("process %d is not running %s", state.PID, target)
+	}
+	if cwd, err := p.Cwd(); err == nil && filepath.Clean(cwd) != filepath.Clean(directory) {
+		return fmt.Errorf("process %d is running in %s, not %s", state.PID, cwd, directory)
+	}
+	return nil
+}
+
+// watchAttached polls an attached process until it exits.
+func (s *Server) watchAttached(state *runtimeState, done chan struct{}) {
+	ticker := time.NewTicker(attachPollInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if processAlive(state) {
+			continue
+		}
+		// Give the tailer a moment to pick up the final log lines.
+		time.Sleep(tailPollInterval * 2)
+
+		s.mu.Lock()
+		requested, sawStopping := s.stopRequested, s.sawStopping
+		s.mu.Unlock()
+
+		now := time.Now()
+		info := ExitInfo{
+			Code:      0,
+			Time:      now,
+			Uptime:    now.Sub(state.StartedAt),
+			Requested: requested,
+		}
+		if !sawStopping {
+			info.Code = -1
+			info.Err = errors.New("attached process exited without logging a shutdown")
+		}
+		s.handleExit(info, done)
+		return
+	}
+}
+
+// processAlive reports whether the recorded process is still running.
+func processAlive(state *runtimeState) bool {
+	p, err := process.NewProcess(int32(state.PID))
+	if err != nil {
+		return false
+	}
+	if running, err := p.IsRunning(); err != nil || !running {
+		return false
+	}
+	if status, err := p.Status(); err == nil && len(status) > 0 && status[0] == process.Zombie {
+		return false
+	}
+	if state.CreateTime != 0 {
+		if createTime, err := p.CreateTime(); err == nil && createTime != state.CreateTime {
+			return false
+		}
+	}
+	return true
+}
+
+// scanExistingLog replays the current latest.log without publishing events to
+// recover readiness and the online player list. It returns the offset to start
+// tailing from.
+func (s *Server) scanExistingLog(path string) (int64, bool, []string) {
+	players := []string{}
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, false, players
+	}
+	defer f.Close()
+
+	ready := false
+	reader := bufio.NewReader(f)
+	var offset int64
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			// Leave a trailing partial line for the tailer.
+			break
+		}
+		offset += int64(len(line))
+
+		message := ParseLogLine(line).Message
+		switch {
+		case doneLoadingPattern.MatchString(message):
+			ready = true
+		case strings.HasSuffix(message, " joined the game"):
+			players = append(players, strings.TrimSuffix(message, " joined the game"))
+		case strings.HasSuffix(message, " left the game"):
+			name := strings.TrimSuffix(message, " left the game")
+			for i, player := range players {
+				if player == name {
+					players = append(players[:i], players[i+1:]...)
+					break
+				}
+			}
+		}
+	}
+	return offset, ready, players
+}
+
+func processCreateTime(pid int) (int64, error) {
+	p, err := process.NewProcess(int32(pid))
+	if err != nil {
+		return 0, err
+	}
+	return p.CreateTime()
+}
+
+// launchTarget returns the jar or @argument file that a recorded command line
+// starts, defaulting to server.jar for state written by older versions.
+func launchTarget(cmdline []string) string {
+	for i, arg := range cmdline {
+		if arg == "-jar" && i+1 < len(cmdline) {
+			return cmdline[i+1]
+		}
+		if strings.HasPrefix(arg, "@") {
+			return arg
+		}
+	}
+	return "server.jar"
+}
+
+func containsArg(args []string, suffix string) bool {
+	for _, arg := range args {
+		if arg == suffix || strings.HasSuffix(arg, string(os.PathSeparator)+suffix) {
+			return true
+		}
+	}
+	return false
+}
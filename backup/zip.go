@@ -2,6 +2,7 @@ package backup
 
 import (
 	"archive/tar"
+	"context"
 	"github.com/klauspost/compress/zstd"
 	"io"
 	"os"
@@ -11,16 +12,24 @@ import (
 )
 
 func CreateBackup(src, destParent string) error {
+	_, err := CreateBackupContext(context.Background(), src, destParent)
+	return err
+}
+
+// CreateBackupContext is like CreateBackup but stops archiving when ctx is done,
+// removing the partially written backup. It returns the path of the new archive.
+func CreateBackupContext(ctx context.Context, src, destParent string) (string, error) {
 	dest := filepath.Join(destParent, "backup-"+time.Now().Format("20060102-150405")+".tar.zst")
 
-	if err := createBackupTar(src, dest); err != nil {
-		return err
+	if err := createBackupTar(ctx, src, dest); err != nil {
+		_ = os.Remove(dest)
+		return "", err
 	}
 
-	return nil
+	return dest, nil
 }
 
-func createBackupTar(src, dest string) error {
+func createBackupTar(ctx context.Context, src, dest string) error {
 	out, err := os.Create(dest)
 	if err != nil {
 		return err
@@ -44,6 +53,9 @@ func createBackupTar(src, dest string) error {
 		if err != nil {
 			return err
 		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 
 		relPath, err := filepath.Rel(src, path)
 		if err != nil {
@@ -0,0 +1,96 @@
+package gomcserver
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func (s *Server) consoleFifoPath() string {
+	return filepath.Join(s.Directory, ".mcserverlib", "console.fifo")
+}
+
+func (s *Server) latestLogPath() string {
+	return filepath.Join(s.Directory, "logs", "latest.log")
+}
+
+// claimDirectory locks the server directory and makes sure no server recorded in
+// it is still running, which would otherwise be started a second time.
+func (s *Server) claimDirectory() error {
+	if err := s.acquireLock(); err != nil {
+		return err
+	}
+	state, err := s.readRuntimeState()
+	if err != nil {
+		return nil
+	}
+	if processAlive(state) {
+		s.releaseLock()
+		return fmt.Errorf("server is already running (pid %d); use Attach to manage it", state.PID)
+	}
+	s.removeRuntimeState()
+	return nil
+}
+
+// setupDetachedIO prepares a process that outlives the controller: it runs in
+// its own session, reads its console from a named FIFO (unless commands go over
+// RCON) and is followed through logs/latest.log instead of pipes. It returns the
+// controller's end of the FIFO, if any, and a function that starts following the
+// log once the process is running.
+func (s *Server) setupDetachedIO(spec *ProcessSpec, transport CommandTransport) (*os.File, func(), error) {
+	var writer *os.File
+	if transport == TransportStdin {
+		child, w, err := createConsoleFifo(s.consoleFifoPath())
+		if err != nil {
+			return nil, nil, err
+		}
+		spec.Stdin = child
+		writer = w
+	}
+
+	// Skip what the previous run left in latest.log; the server rotates the file
+	// on startup and the tailer follows the new one from the beginning.
+	logPath := s.latestLogPath()
+	var offset int64
+	if info, err := os.Stat(logPath); err == nil {
+		offset = info.Size()
+	}
+
+	return writer, func() {
+		s.startTail(logPath, offset)
+	}, nil
+}
+
+// startTail follows the server log in place of a stdout pipe.
+func (s *Server) startTail(path string, offset int64) {
+	stop := make(chan struct{})
+	s.mu.Lock()
+	s.stopTail = stop
+	s.mu.Unlock()
+	go tailFile(path, offset, stop, s.handleStdoutLine)
+}
+
+// stopTailing stops following the server log, if it is being followed.
+func (s *Server) stopTailing() {
+	s.mu.Lock()
+	stop := s.stopTail
+	s.stopTail = nil
+	s.mu.Unlock()
+	if stop != nil {
+		close(stop)
+	}
+}
+
+// closeConsoleFifo closes the controller's end of the console FIFO.
+func (s *Server) closeConsoleFifo() {
+	s.mu.Lock()
+	fifo := s.consoleFifo
+	if fifo != nil {
+		s.consoleFifo = nil
+		s.stdinPipe = nil
+	}
+	s.mu.Unlock()
+	if fifo != nil {
+		_ = fifo.Close()
+	}
+}
@@ -0,0 +1,47 @@
+//go:build !windows
+
+package gomcserver
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// consoleFifoSupported reports whether detached servers can read their console
+// from a named FIFO on this platform.
+const consoleFifoSupported = true
+
+// configureDetached starts the process in its own session so that it does not
+// receive signals aimed at the controller's process group or terminal.
+func configureDetached(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
+
+// createConsoleFifo creates a named FIFO at path and returns the end to hand to
+// the child as stdin together with a writer for sending commands. The child end
+// is opened read-write so the FIFO never reports EOF, even while no controller
+// is attached.
+func createConsoleFifo(path string) (child *os.File, writer *os.File, err error) {
+	_ = os.Remove(path)
+	if err := syscall.Mkfifo(path, 0600); err != nil {
+		return nil, nil, fmt.Errorf("failed to create console fifo: %w", err)
+	}
+	child, err = os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open console fifo: %w", err)
+	}
+	writer, err = os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		_ = child.Close()
+		return nil, nil, fmt.Errorf("failed to open console fifo for writing: %w", err)
+	}
+	return child, writer, nil
+}
+
+// openConsoleFifo opens an existing console FIFO for writing. It fails instead of
+// blocking if no process holds the read end.
+func openConsoleFifo(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+}
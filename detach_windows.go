@@ -0,0 +1,32 @@
+//go:build windows
+
+package gomcserver
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// consoleFifoSupported reports whether detached servers can read their console
+// from a named FIFO on this platform.
+const consoleFifoSupported = false
+
+const detachedProcess = 0x00000008
+
+// configureDetached starts the process in a new process group without a console
+// so that it does not receive the controller's console control events.
+func configureDetached(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP | detachedProcess,
+	}
+}
+
+func createConsoleFifo(path string) (*os.File, *os.File, error) {
+	return nil, nil, errors.New("console fifos are not supported on windows")
+}
+
+func openConsoleFifo(path string) (*os.File, error) {
+	return nil, errors.New("console fifos are not supported on windows")
+}
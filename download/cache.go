@@ -0,0 +1,375 @@
+package download
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// JarCache is a content-addressed store of server jars shared by all server
+// directories that use the same cache directory. Jars are stored under
+// Dir/jars/<algorithm>/<hash>.jar and hard linked into server directories,
+// falling back to a copy where links are not possible.
+type JarCache struct {
+	Dir string // cache directory; "~/" is expanded
+}
+
+// CacheEntry describes a jar in a JarCache.
+type CacheEntry struct {
+	Algorithm string    `json:"algorithm"` // "sha1" or "sha256"
+	Hash      string    `json:"hash"`
+	Path      string    `json:"-"`
+	Size      int64     `json:"size"`
+	Source    string    `json:"source,omitempty"` // URL the jar was downloaded from
+	AddedAt   time.Time `json:"addedAt"`
+	LastUsed  time.Time `json:"lastUsed"`
+	Users     []string  `json:"users,omitempty"` // files the jar was installed as
+}
+
+// PruneOptions selects which entries JarCache.Prune removes.
+type PruneOptions struct {
+	OlderThan    time.Duration // only entries unused for longer than this
+	IncludeInUse bool          // also remove jars still installed in a server directory
+}
+
+// Fetch makes dest a copy of the jar with the given digest, which must be a
+// "sha1" or "sha256" hex digest. An existing dest that already matches is kept,
+// a cached jar that still has the digest is linked in, and otherwise url is
+// downloaded into the cache first with DefaultDownloader.
+func (c *JarCache) Fetch(ctx context.Context, url, algorithm, digest, dest string) error {
+	return c.fetch(ctx, DefaultDownloader, url, algorithm, digest, dest)
+}
+
+func (c *JarCache) fetch(ctx context.Context, d *Downloader, url, algorithm, digest, dest string) error {
+	newHash, err := hashFunc(algorithm)
+	if err != nil {
+		return err
+	}
+	digest = strings.ToLower(digest)
+	object := c.objectPath(algorithm, digest)
+	if err := os.MkdirAll(filepath.Dir(object), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create jar cache: %w", err)
+	}
+
+	unlock, err := c.lock()
+	if err != nil {
+		return err
+	}
+	defer func() { unlock() }()
+
+	if fileHasDigest(dest, newHash(), digest) {
+		// Adopt jars installed before the cache existed so other servers can
+		// share them.
+		if _, err := os.Stat(object); os.IsNotExist(err) {
+			_ = linkOrCopy(dest, object)
+		}
+		return c.recordUse(algorithm, digest, url, dest)
+	}
+
+	// A cached object that no longer matches its digest, for example one
+	// truncated by a crash or edited through a hardlink, is fetched again. The
+	// cache is not locked during the download, so a concurrent Prune may remove
+	// the new object before it is linked; it is then fetched once more.
+	for attempt := 0; !fileHasDigest(object, newHash(), digest); attempt++ {
+		if attempt == maxFetchAttempts {
+			return fmt.Errorf("cached jar %s was removed while it was being installed", digest)
+		}
+		if err := os.Remove(object); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove corrupt cached jar: %w", err)
+		}
+		unlock()
+		unlock = func() {}
+		if err := d.download(ctx, url, object, newHash, algorithm, digest); err != nil {
+			return err
+		}
+		if unlock, err = c.lock(); err != nil {
+			unlock = func() {}
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), os.ModePerm); err != nil {
+		return err
+	}
+	if err := linkOrCopy(object, dest); err != nil {
+		return fmt.Errorf("failed to install cached jar: %w", err)
+	}
+	return c.recordUse(algorithm, digest, url, dest)
+}
+
+// maxFetchAttempts bounds how often fetch downloads a jar that keeps being
+// pruned before it can be linked.
+const maxFetchAttempts = 3
+
+// lock takes the cache's lock file, which serialises changes to the entry
+// metadata and to the set of cached jars between processes sharing the cache.
+// It blocks until the lock is free and returns the function that releases it.
+func (c *JarCache) lock() (func(), error) {
+	if err := os.MkdirAll(c.root(), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create jar cache: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(c.root(), ".lock"), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open jar cache lock: %w", err)
+	}
+	if err := lockFile(f); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to lock jar cache: %w", err)
+	}
+	return func() {
+		unlockFile(f)
+		_ = f.Close()
+	}, nil
+}
+
+// List returns the cached jars, most recently used first.
+func (c *JarCache) List() ([]CacheEntry, error) {
+	var entries []CacheEntry
+	for _, algorithm := range []string{"sha1", "sha256"} {
+		dir := filepath.Join(c.root(), algorithm)
+		files, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			name := file.Name()
+			if !strings.HasSuffix(name, ".jar") {
+				continue
+			}
+			entry, err := c.entry(algorithm, strings.TrimSuffix(name, ".jar"))
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, *entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastUsed.After(entries[j].LastUsed)
+	})
+	return entries, nil
+}
+
+// Prune removes the entries selected by opts and returns them. Server
+// directories keep their own link or copy of a removed jar.
+func (c *JarCache) Prune(opts PruneOptions) ([]CacheEntry, error) {
+	unlock, err := c.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return c.prune(opts)
+}
+
+func (c *JarCache) prune(opts PruneOptions) ([]CacheEntry, error) {
+	entries, err := c.List()
+	if err != nil {
+		return nil, err
+	}
+	var removed []CacheEntry
+	for _, entry := range entries {
+		if time.Since(entry.LastUsed) < opts.OlderThan {
+			continue
+		}
+		if !opts.IncludeInUse && len(c.liveUsers(&entry)) > 0 {
+			continue
+		}
+		if err := os.Remove(entry.Path); err != nil && !os.IsNotExist(err) {
+			return removed, err
+		}
+		_ = os.Remove(c.metaPath(entry.Algorithm, entry.Hash))
+		removed = append(removed, entry)
+	}
+	return removed, nil
+}
+
+// GC forgets users whose installed jar has since been replaced or deleted and
+// removes the jars no server directory uses any more.
+func (c *JarCache) GC() ([]CacheEntry, error) {
+	unlock, err := c.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	entries, err := c.List()
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		live := c.liveUsers(&entries[i])
+		if len(live) != len(entries[i].Users) {
+			entries[i].Users = live
+			if err := writeJSONFile(c.metaPath(entries[i].Algorithm, entries[i].Hash), entries[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return c.prune(PruneOptions{})
+}
+
+func (c *JarCache) root() string {
+	return filepath.Join(expandHomeDirectory(c.Dir), "jars")
+}
+
+func (c *JarCache) objectPath(algorithm, digest string) string {
+	return filepath.Join(c.root(), algorithm, digest+".jar")
+}
+
+func (c *JarCache) metaPath(algorithm, digest string) string {
+	return filepath.Join(c.root(), algorithm, digest+".json")
+}
+
+func (c *JarCache) entry(algorithm, digest string) (*CacheEntry, error) {
+	path := c.objectPath(algorithm, digest)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	entry := &CacheEntry{}
+	if err := loadJSONFile(c.metaPath(algorithm, digest), entry); err != nil {
+		entry.AddedAt = info.ModTime()
+		entry.LastUsed = info.ModTime()
+	}
+	entry.Algorithm = algorithm
+	entry.Hash = digest
+	entry.Path = path
+	entry.Size = info.Size()
+	return entry, nil
+}
+
+// recordUse notes that dest uses the jar. The caller holds the cache lock.
+func (c *JarCache) recordUse(algorithm, digest, source, dest string) error {
+	entry, err := c.entry(algorithm, digest)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if entry.AddedAt.IsZero() {
+		entry.AddedAt = now
+	}
+	entry.LastUsed = now
+	if source != "" {
+		entry.Source = source
+	}
+	if abs, err := filepath.Abs(dest); err == nil {
+		dest = abs
+	}
+	found := false
+	for _, user := range entry.Users {
+		if user == dest {
+			found = true
+			break
+		}
+	}
+	if !found {
+		entry.Users = append(entry.Users, dest)
+	}
+	return writeJSONFile(c.metaPath(algorithm, digest), entry)
+}
+
+// liveUsers returns the users of entry that still hold the cached jar.
+func (c *JarCache) liveUsers(entry *CacheEntry) []string {
+	newHash, err := hashFunc(entry.Algorithm)
+	if err != nil {
+		return nil
+	}
+	object, err := os.Stat(entry.Path)
+	if err != nil {
+		return nil
+	}
+	var live []string
+	for _, user := range entry.Users {
+		info, err := os.Stat(user)
+		if err != nil {
+			continue
+		}
+		if os.SameFile(object, info) || (info.Size() == entry.Size && fileHasDigest(user, newHash(), entry.Hash)) {
+			live = append(live, user)
+		}
+	}
+	return live
+}
+
+// fetchVerified downloads url to dest unless dest already has the expected
+// digest, going through jars when it is set and supports the algorithm. With
+// no digest the file is always downloaded.
+func fetchVerified(ctx context.Context, d *Downloader, jars *JarCache, url string, newHash func() hash.Hash, algorithm, digest, dest string) error {
+	if digest == "" {
+		return d.download(ctx, url, dest, newHash, algorithm, "")
+	}
+	if _, err := hashFunc(algorithm); err == nil && jars != nil {
+		return jars.fetch(ctx, d, url, algorithm, digest, dest)
+	}
+	if fileHasDigest(dest, newHash(), digest) {
+		return nil
+	}
+	return d.download(ctx, url, dest, newHash, algorithm, digest)
+}
+
+func hashFunc(algorithm string) (func() hash.Hash, error) {
+	switch algorithm {
+	case "sha1":
+		return sha1.New, nil
+	case "sha256":
+		return sha256.New, nil
+	default:
+		return nil, fmt.Errorf("unsupported cache digest algorithm %q", algorithm)
+	}
+}
+
+func fileHasDigest(path string, hasher hash.Hash, digest string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return false
+	}
+	return strings.EqualFold(fmt.Sprintf("%x", hasher.Sum(nil)), digest)
+}
+
+// linkOrCopy atomically replaces dest with a hard link to src, or with a copy
+// if src and dest are on different filesystems or links are not supported.
+func linkOrCopy(src, dest string) error {
+	tmp := fmt.Sprintf("%s.%d.tmp", dest, os.Getpid())
+	_ = os.Remove(tmp)
+	if err := os.Link(src, tmp); err != nil {
+		if err := copyFile(src, tmp); err != nil {
+			_ = os.Remove(tmp)
+			return err
+		}
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+func copyFile(src, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
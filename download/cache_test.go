@@ -0,0 +1,97 @@
+package download
+
+import (
+	"context"
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestJarCacheReplacesCorruptObject(t *testing.T) {
+	jar := "server jar"
+	sum := sha256.Sum256([]byte(jar))
+	digest := hexDigest(sum[:])
+	api := newFakeAPI(t, map[string]string{"/server.jar": jar})
+	d := &Downloader{Client: api.Client(), Retries: -1}
+
+	cache := &JarCache{Dir: t.TempDir()}
+	object := cache.objectPath("sha256", digest)
+	if err := os.MkdirAll(filepath.Dir(object), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(object, []byte("truncated"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dest := filepath.Join(t.TempDir(), "server.jar")
+	if err := cache.fetch(context.Background(), d, api.URL+"/server.jar", "sha256", digest, dest); err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	assertFile(t, dest, jar)
+	assertFile(t, object, jar)
+	if !api.fetched("/server.jar") {
+		t.Error("corrupt object was linked instead of downloaded again")
+	}
+
+	// A valid object is reused without downloading.
+	api.requested = nil
+	other := filepath.Join(t.TempDir(), "server.jar")
+	if err := cache.fetch(context.Background(), d, api.URL+"/server.jar", "sha256", digest, other); err != nil {
+		t.Fatalf("second fetch: %v", err)
+	}
+	assertFile(t, other, jar)
+	if api.fetched("/server.jar") {
+		t.Error("valid cached object was downloaded again")
+	}
+}
+
+func TestJarCacheConcurrentUsers(t *testing.T) {
+	jar := "server jar"
+	sum := sha256.Sum256([]byte(jar))
+	digest := hexDigest(sum[:])
+	cache := &JarCache{Dir: t.TempDir()}
+
+	// Servers that already have a cached jar only record themselves as users,
+	// so their metadata updates race as closely as possible.
+	const servers = 64
+	var wg sync.WaitGroup
+	for i := 0; i < servers; i++ {
+		dest := filepath.Join(t.TempDir(), "server.jar")
+		if err := os.WriteFile(dest, []byte(jar), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			// The first server seeds the cache.
+			if err := cache.fetch(context.Background(), DefaultDownloader, "https://example.invalid/server.jar", "sha256", digest, dest); err != nil {
+				t.Fatalf("fetch: %v", err)
+			}
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := cache.fetch(context.Background(), DefaultDownloader, "https://example.invalid/server.jar", "sha256", digest, dest); err != nil {
+				t.Errorf("fetch: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	entry, err := cache.entry("sha256", digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entry.Users) != servers {
+		t.Fatalf("entry has %d users, want %d", len(entry.Users), servers)
+	}
+	// Every server still uses the jar, so GC keeps it.
+	removed, err := cache.GC()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 0 {
+		t.Errorf("GC removed %d jars in use", len(removed))
+	}
+}
@@ -0,0 +1,349 @@
+package download
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Defaults used by a Downloader whose fields are zero.
+const (
+	DefaultRetries      = 3
+	DefaultBackoff      = time.Second
+	DefaultStallTimeout = 30 * time.Second
+)
+
+const (
+	maxBackoff       = 30 * time.Second
+	progressInterval = 250 * time.Millisecond
+)
+
+// DefaultDownloader is used by DownloadFile, DownloadJSON and the providers
+// unless they are given another Downloader.
+var DefaultDownloader = &Downloader{}
+
+var defaultClient = func() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = DefaultStallTimeout
+	return &http.Client{Transport: transport}
+}()
+
+// Downloader fetches files over HTTP. Files are written to a temporary file
+// next to the output and renamed into place once complete and verified. Failed
+// transfers are retried with exponential backoff, resuming with a Range request
+// where the server supports it. The zero value is ready to use.
+type Downloader struct {
+	Client       *http.Client   // nil uses a client with a response header timeout
+	Retries      int            // retries after a failed attempt, DefaultRetries if 0, none if negative
+	Backoff      time.Duration  // delay before the first retry, doubled for each one; DefaultBackoff if 0
+	StallTimeout time.Duration  // abort an attempt that receives nothing for this long, DefaultStallTimeout if 0
+	Progress     func(Progress) // called periodically while a file downloads, may be nil
+}
+
+// Progress reports the state of a file download.
+type Progress struct {
+	URL   string
+	Bytes int64   // bytes received so far, including resumed bytes
+	Total int64   // size of the file, or -1 if the server did not say
+	Rate  float64 // average bytes per second since the download began
+	Done  bool    // the transfer has finished; reported once per download
+}
+
+// HTTPError is returned when a server answers with a non-2xx status.
+type HTTPError struct {
+	URL        string
+	StatusCode int
+	Status     string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("GET %s: %s", e.URL, e.Status)
+}
+
+var errStalled = errors.New("download stalled")
+
+// permanentError marks a failure that retrying cannot fix.
+type permanentError struct {
+	error
+}
+
+func (e permanentError) Unwrap() error {
+	return e.error
+}
+
+// DownloadFile downloads url to output, verifying its SHA-1 if expectedSha1 is
+// not empty.
+func (d *Downloader) DownloadFile(ctx context.Context, url, output, expectedSha1 string) error {
+	return d.download(ctx, url, output, sha1.New, "sha1", expectedSha1)
+}
+
+// download fetches url into a temporary file, verifies its digest if expected
+// is not empty and renames it to output.
+func (d *Downloader) download(ctx context.Context, url, output string, newHash func() hash.Hash, algorithm, expected string) error {
+	part, err := os.CreateTemp(filepath.Dir(output), filepath.Base(output)+".*.part")
+	if err != nil {
+		return fmt.Errorf("file create failed: %w", err)
+	}
+	partPath := part.Name()
+	defer func() {
+		_ = part.Close()
+		_ = os.Remove(partPath)
+	}()
+
+	t := &transfer{url: url, progress: d.Progress, start: time.Now(), total: -1}
+	if err := d.retry(ctx, func() error { return d.fetchInto(ctx, url, part, t) }); err != nil {
+		return err
+	}
+	t.report(true)
+
+	if expected != "" {
+		hasher := newHash()
+		if _, err := part.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("file read failed: %w", err)
+		}
+		if _, err := io.Copy(hasher, part); err != nil {
+			return fmt.Errorf("file read failed: %w", err)
+		}
+		actual := fmt.Sprintf("%x", hasher.Sum(nil))
+		if !strings.EqualFold(actual, expected) {
+			return fmt.Errorf("%s mismatch: got %s, expected %s", algorithm, actual, expected)
+		}
+	}
+
+	if err := part.Close(); err != nil {
+		return fmt.Errorf("file write failed: %w", err)
+	}
+	if err := os.Chmod(partPath, 0644); err != nil {
+		return fmt.Errorf("file write failed: %w", err)
+	}
+	// Renaming also replaces rather than rewrites output, which may be a hard
+	// link into a JarCache.
+	if err := os.Rename(partPath, output); err != nil {
+		return fmt.Errorf("file write failed: %w", err)
+	}
+	return nil
+}
+
+// fetchInto makes one attempt at downloading url, appending to what part
+// already holds when the server honours a Range request.
+func (d *Downloader) fetchInto(ctx context.Context, url string, part *os.File, t *transfer) error {
+	offset, err := part.Seek(0, io.SeekEnd)
+	if err != nil {
+		return permanentError{fmt.Errorf("file write failed: %w", err)}
+	}
+
+	attemptCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stallTimeout := d.StallTimeout
+	if stallTimeout == 0 {
+		stallTimeout = DefaultStallTimeout
+	}
+	stall := time.AfterFunc(stallTimeout, cancel)
+	defer stall.Stop()
+
+	req, err := http.NewRequestWithContext(attemptCtx, http.MethodGet, url, nil)
+	if err != nil {
+		return permanentError{err}
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := d.client().Do(req)
+	if err != nil {
+		return stalledOr(ctx, attemptCtx, fmt.Errorf("http get failed: %w", err))
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		if !strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", offset)) {
+			return restart(part, errors.New("server resumed at the wrong offset"))
+		}
+		t.total = -1
+		if resp.ContentLength >= 0 {
+			t.total = offset + resp.ContentLength
+		}
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		return restart(part, errors.New("server rejected resume"))
+	case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+		// The server sent the whole file, so drop anything kept from before.
+		if offset > 0 {
+			if err := truncate(part); err != nil {
+				return permanentError{err}
+			}
+			offset = 0
+		}
+		t.total = resp.ContentLength
+	default:
+		return statusError(url, resp)
+	}
+	t.bytes = offset
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, readErr := resp.Body.Read(buf)
+		if n > 0 {
+			if _, err := part.Write(buf[:n]); err != nil {
+				return permanentError{fmt.Errorf("file write failed: %w", err)}
+			}
+			stall.Reset(stallTimeout)
+			t.bytes += int64(n)
+			t.report(false)
+		}
+		if readErr == io.EOF {
+			return nil
+		}
+		if readErr != nil {
+			return stalledOr(ctx, attemptCtx, fmt.Errorf("download failed: %w", readErr))
+		}
+	}
+}
+
+// retry runs attempt until it succeeds, fails permanently or the retries are
+// used up, sleeping with exponential backoff in between.
+func (d *Downloader) retry(ctx context.Context, attempt func() error) error {
+	retries := d.Retries
+	if retries == 0 {
+		retries = DefaultRetries
+	}
+	delay := d.Backoff
+	if delay == 0 {
+		delay = DefaultBackoff
+	}
+	for i := 0; ; i++ {
+		err := attempt()
+		var permanent permanentError
+		if errors.As(err, &permanent) {
+			return permanent.error
+		}
+		if err == nil || ctx.Err() != nil || i >= retries {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		delay = min(delay*2, maxBackoff)
+	}
+}
+
+func (d *Downloader) client() *http.Client {
+	if d.Client != nil {
+		return d.Client
+	}
+	return defaultClient
+}
+
+// getJSON downloads url and unmarshals it into a T.
+func getJSON[T any](ctx context.Context, d *Downloader, url string) (*T, error) {
+	var result T
+	err := d.retry(ctx, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return permanentError{err}
+		}
+		resp, err := d.client().Do(req)
+		if err != nil {
+			return fmt.Errorf("http get failed: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return statusError(url, resp)
+		}
+
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("read failed: %w", err)
+		}
+		if err := json.Unmarshal(data, &result); err != nil {
+			return permanentError{fmt.Errorf("json unmarshal failed: %w", err)}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// statusError converts a non-2xx response into an HTTPError. Only server
+// errors, timeouts and rate limiting are worth retrying.
+func statusError(url string, resp *http.Response) error {
+	err := &HTTPError{URL: url, StatusCode: resp.StatusCode, Status: resp.Status}
+	switch {
+	case resp.StatusCode >= 500, resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests:
+		return err
+	default:
+		return permanentError{err}
+	}
+}
+
+// stalledOr reports errStalled if attemptCtx was cancelled by the stall timer
+// rather than by the caller.
+func stalledOr(ctx, attemptCtx context.Context, err error) error {
+	if ctx.Err() == nil && attemptCtx.Err() != nil {
+		return errStalled
+	}
+	return err
+}
+
+// restart empties part so that the next attempt downloads the whole file.
+func restart(part *os.File, err error) error {
+	if truncErr := truncate(part); truncErr != nil {
+		return permanentError{truncErr}
+	}
+	return err
+}
+
+func truncate(part *os.File) error {
+	if err := part.Truncate(0); err != nil {
+		return fmt.Errorf("file write failed: %w", err)
+	}
+	if _, err := part.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("file write failed: %w", err)
+	}
+	return nil
+}
+
+// transfer tracks one file download for progress reporting.
+type transfer struct {
+	url      string
+	progress func(Progress)
+	start    time.Time
+	last     time.Time
+	bytes    int64
+	total    int64
+}
+
+func (t *transfer) report(done bool) {
+	if t.progress == nil {
+		return
+	}
+	now := time.Now()
+	if !done && now.Sub(t.last) < progressInterval {
+		return
+	}
+	t.last = now
+	rate := 0.0
+	if elapsed := now.Sub(t.start).Seconds(); elapsed > 0 {
+		rate = float64(t.bytes) / elapsed
+	}
+	total := t.total
+	if done && total < 0 {
+		total = t.bytes
+	}
+	t.progress(Progress{URL: t.url, Bytes: t.bytes, Total: total, Rate: rate, Done: done})
+}
@@ -0,0 +1,257 @@
+package download
+
+import (
+	"context"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// payload is the file served by the scripted servers.
+var payload = strings.Repeat("0123456789abcdef", 4096)
+
+// scriptedServer answers the nth request with the nth handler, repeating the
+// last one, and records the Range header of every request.
+type scriptedServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	handlers []http.HandlerFunc
+	ranges   []string
+}
+
+func newScriptedServer(t *testing.T, handlers ...http.HandlerFunc) *scriptedServer {
+	t.Helper()
+	s := &scriptedServer{handlers: handlers}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		n := len(s.ranges)
+		s.ranges = append(s.ranges, r.Header.Get("Range"))
+		s.mu.Unlock()
+		s.handlers[min(n, len(s.handlers)-1)](w, r)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *scriptedServer) requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.ranges...)
+}
+
+func (s *scriptedServer) downloader() *Downloader {
+	return &Downloader{Client: s.Client(), Backoff: time.Millisecond}
+}
+
+// serveFull sends the whole payload.
+func serveFull(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Length", strconv.Itoa(len(payload)))
+	_, _ = w.Write([]byte(payload))
+}
+
+// serveCut announces the whole payload but drops the connection after half.
+func serveCut(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Length", strconv.Itoa(len(payload)))
+	_, _ = w.Write([]byte(payload[:len(payload)/2]))
+}
+
+// serveRange honours a Range request with a 206 response.
+func serveRange(w http.ResponseWriter, r *http.Request) {
+	var offset int
+	if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-", &offset); err != nil {
+		serveFull(w, r)
+		return
+	}
+	w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, len(payload)-1, len(payload)))
+	w.Header().Set("Content-Length", strconv.Itoa(len(payload)-offset))
+	w.WriteHeader(http.StatusPartialContent)
+	_, _ = w.Write([]byte(payload[offset:]))
+}
+
+func serveStatus(code int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(code)
+	}
+}
+
+func payloadSha1() string {
+	return fmt.Sprintf("%x", sha1.Sum([]byte(payload)))
+}
+
+// download fetches the test file and checks that no temporary file is left.
+func download(t *testing.T, d *Downloader, url, expected string) (string, error) {
+	t.Helper()
+	dir := t.TempDir()
+	output := filepath.Join(dir, "server.jar")
+	err := d.DownloadFile(context.Background(), url, output, expected)
+	if parts, _ := filepath.Glob(filepath.Join(dir, "*.part")); len(parts) > 0 {
+		t.Errorf("temporary files left behind: %q", parts)
+	}
+	return output, err
+}
+
+func TestDownloadRetriesServerError(t *testing.T) {
+	srv := newScriptedServer(t, serveStatus(http.StatusBadGateway), serveFull)
+	output, err := download(t, srv.downloader(), srv.URL, payloadSha1())
+	if err != nil {
+		t.Fatalf("DownloadFile: %v", err)
+	}
+	assertFile(t, output, payload)
+	if n := len(srv.requests()); n != 2 {
+		t.Errorf("requests = %d, want 2", n)
+	}
+}
+
+func TestDownloadClientErrorIsPermanent(t *testing.T) {
+	srv := newScriptedServer(t, serveStatus(http.StatusNotFound), serveFull)
+	output, err := download(t, srv.downloader(), srv.URL, "")
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("DownloadFile error = %v, want an HTTPError 404", err)
+	}
+	var permanent permanentError
+	if errors.As(err, &permanent) {
+		t.Error("permanentError leaked to the caller")
+	}
+	if n := len(srv.requests()); n != 1 {
+		t.Errorf("requests = %d, want 1", n)
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Error("output created for a failed download")
+	}
+}
+
+func TestDownloadRetriesGiveUp(t *testing.T) {
+	srv := newScriptedServer(t, serveStatus(http.StatusServiceUnavailable))
+	d := srv.downloader()
+	d.Retries = 2
+	if _, err := download(t, d, srv.URL, ""); err == nil {
+		t.Fatal("DownloadFile succeeded against a failing server")
+	}
+	if n := len(srv.requests()); n != 3 {
+		t.Errorf("requests = %d, want 3", n)
+	}
+}
+
+func TestDownloadResumesWithRange(t *testing.T) {
+	srv := newScriptedServer(t, serveCut, serveRange)
+	var progress []Progress
+	d := srv.downloader()
+	d.Progress = func(p Progress) { progress = append(progress, p) }
+
+	output, err := download(t, d, srv.URL, payloadSha1())
+	if err != nil {
+		t.Fatalf("DownloadFile: %v", err)
+	}
+	assertFile(t, output, payload)
+	requests := srv.requests()
+	if want := fmt.Sprintf("bytes=%d-", len(payload)/2); len(requests) != 2 || requests[1] != want {
+		t.Errorf("Range headers = %q, want a resume with %q", requests, want)
+	}
+
+	// Progress counts resumed bytes, never goes backwards and ends with Done.
+	if len(progress) == 0 {
+		t.Fatal("no progress reported")
+	}
+	for i := 1; i < len(progress); i++ {
+		if progress[i].Bytes < progress[i-1].Bytes {
+			t.Errorf("progress went backwards: %d after %d", progress[i].Bytes, progress[i-1].Bytes)
+		}
+		if progress[i-1].Done {
+			t.Errorf("progress reported after Done")
+		}
+	}
+	last := progress[len(progress)-1]
+	if !last.Done || last.Bytes != int64(len(payload)) || last.Total != int64(len(payload)) {
+		t.Errorf("last progress = %+v, want Done with %d bytes", last, len(payload))
+	}
+}
+
+func TestDownloadRestartsWhenRangeIgnored(t *testing.T) {
+	srv := newScriptedServer(t, serveCut, serveFull)
+	output, err := download(t, srv.downloader(), srv.URL, payloadSha1())
+	if err != nil {
+		t.Fatalf("DownloadFile: %v", err)
+	}
+	// The 200 response replaces the partial file instead of being appended.
+	assertFile(t, output, payload)
+	if requests := srv.requests(); len(requests) != 2 || requests[1] == "" {
+		t.Errorf("Range headers = %q, want a resume attempt", requests)
+	}
+}
+
+func TestDownloadRestartsAfterRangeNotSatisfiable(t *testing.T) {
+	srv := newScriptedServer(t, serveCut, serveStatus(http.StatusRequestedRangeNotSatisfiable), serveFull)
+	output, err := download(t, srv.downloader(), srv.URL, payloadSha1())
+	if err != nil {
+		t.Fatalf("DownloadFile: %v", err)
+	}
+	assertFile(t, output, payload)
+	requests := srv.requests()
+	if len(requests) != 3 || requests[1] == "" || requests[2] != "" {
+		t.Errorf("Range headers = %q, want a rejected resume followed by a full download", requests)
+	}
+}
+
+func TestDownloadHashMismatchKeepsOutput(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "server.jar")
+	if err := os.WriteFile(output, []byte("old jar"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var sawOutput string
+	srv := newScriptedServer(t, func(w http.ResponseWriter, r *http.Request) {
+		// The output is untouched while the download is in flight.
+		data, _ := os.ReadFile(output)
+		sawOutput = string(data)
+		serveFull(w, r)
+	})
+
+	err := srv.downloader().DownloadFile(context.Background(), srv.URL, output, strings.Repeat("0", 40))
+	if err == nil || !strings.Contains(err.Error(), "sha1 mismatch") {
+		t.Fatalf("DownloadFile error = %v, want a sha1 mismatch", err)
+	}
+	if sawOutput != "old jar" {
+		t.Errorf("output during the download = %q, want the old jar", sawOutput)
+	}
+	assertFile(t, output, "old jar")
+	if parts, _ := filepath.Glob(filepath.Join(dir, "*.part")); len(parts) > 0 {
+		t.Errorf("temporary files left behind: %q", parts)
+	}
+
+	// A verified download replaces the output.
+	if err := srv.downloader().DownloadFile(context.Background(), srv.URL, output, payloadSha1()); err != nil {
+		t.Fatalf("DownloadFile: %v", err)
+	}
+	assertFile(t, output, payload)
+}
+
+func TestDownloadStallTimeout(t *testing.T) {
+	srv := newScriptedServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(payload)))
+		_, _ = w.Write([]byte(payload[:1024]))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+	d := srv.downloader()
+	d.Retries = -1
+	d.StallTimeout = 100 * time.Millisecond
+
+	start := time.Now()
+	_, err := download(t, d, srv.URL, "")
+	if !errors.Is(err, errStalled) {
+		t.Fatalf("DownloadFile error = %v, want errStalled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("stalled download took %v to fail", elapsed)
+	}
+}
@@ -0,0 +1,86 @@
+package download
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultFabricURL is the Fabric meta API used when Fabric.BaseURL is empty.
+const DefaultFabricURL = "https://meta.fabricmc.net"
+
+// fabricLauncherJar is the name of the Fabric server launcher. It downloads the
+// vanilla jar as server.jar on first start, so it must not use that name.
+const fabricLauncherJar = "fabric-server-launch.jar"
+
+// Fabric installs the Fabric server launcher from the Fabric meta API.
+type Fabric struct {
+	BaseURL   string // API root, DefaultFabricURL if empty
+	Loader    string // loader version, the latest stable if empty
+	Installer string // installer version, the latest stable if empty
+}
+
+type fabricComponent struct {
+	Version string `json:"version"`
+	Stable  bool   `json:"stable"`
+}
+
+// Name implements Provider.
+func (f *Fabric) Name() string {
+	return "fabric"
+}
+
+// Install implements Provider.
+func (f *Fabric) Install(ctx context.Context, req InstallRequest) (*Installation, error) {
+	base := strings.TrimRight(f.BaseURL, "/")
+	if base == "" {
+		base = DefaultFabricURL
+	}
+
+	version := req.Version
+	if version == "" || version == "latest" {
+		latest, err := latestStableFabric(ctx, req.downloader(), base+"/v2/versions/game")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list fabric game versions: %w", err)
+		}
+		version = latest
+	}
+	loader := f.Loader
+	if loader == "" {
+		latest, err := latestStableFabric(ctx, req.downloader(), base+"/v2/versions/loader")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list fabric loader versions: %w", err)
+		}
+		loader = latest
+	}
+	installer := f.Installer
+	if installer == "" {
+		latest, err := latestStableFabric(ctx, req.downloader(), base+"/v2/versions/installer")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list fabric installer versions: %w", err)
+		}
+		installer = latest
+	}
+
+	jarURL := fmt.Sprintf("%s/v2/versions/loader/%s/%s/%s/server/jar", base, version, loader, installer)
+	if err := req.downloader().DownloadFile(ctx, jarURL, filepath.Join(req.Directory, fabricLauncherJar), ""); err != nil {
+		return nil, fmt.Errorf("failed to download fabric server launcher: %w", err)
+	}
+	return &Installation{Software: f.Name(), Version: version, Build: loader, Jar: fabricLauncherJar}, nil
+}
+
+// latestStableFabric returns the first stable entry of a Fabric meta version
+// list, which is ordered newest first.
+func latestStableFabric(ctx context.Context, d *Downloader, url string) (string, error) {
+	components, err := getJSON[[]fabricComponent](ctx, d, url)
+	if err != nil {
+		return "", err
+	}
+	for _, c := range *components {
+		if c.Stable {
+			return c.Version, nil
+		}
+	}
+	return "", fmt.Errorf("no stable version listed at %s", url)
+}
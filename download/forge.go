@@ -0,0 +1,317 @@
+package download
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultForgeMavenURL and DefaultForgePromotionsURL are used when the Forge
+// fields are empty.
+const (
+	DefaultForgeMavenURL      = "https://maven.minecraftforge.net"
+	DefaultForgePromotionsURL = "https://files.minecraftforge.net/net/minecraftforge/forge/promotions_slim.json"
+)
+
+// DefaultNeoForgeMavenURL is the NeoForged maven used when
+// NeoForge.MavenURL is empty.
+const DefaultNeoForgeMavenURL = "https://maven.neoforged.net/releases"
+
+// Forge installs Minecraft Forge by running its installer with
+// --installServer. The installer needs Java, which is requested through
+// InstallRequest.Java.
+type Forge struct {
+	MavenURL      string // maven root, DefaultForgeMavenURL if empty
+	PromotionsURL string // promotions index, DefaultForgePromotionsURL if empty
+	Version       string // Forge version, e.g. "47.2.0"; the recommended build if empty
+}
+
+type forgePromotions struct {
+	Promos map[string]string `json:"promos"`
+}
+
+// Name implements Provider.
+func (f *Forge) Name() string {
+	return "forge"
+}
+
+// Install implements Provider.
+func (f *Forge) Install(ctx context.Context, req InstallRequest) (*Installation, error) {
+	gameVersion, build := req.Version, f.Version
+	if gameVersion == "" || gameVersion == "latest" || build == "" {
+		promotionsURL := f.PromotionsURL
+		if promotionsURL == "" {
+			promotionsURL = DefaultForgePromotionsURL
+		}
+		promotions, err := getJSON[forgePromotions](ctx, req.downloader(), promotionsURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download forge promotions: %w", err)
+		}
+		if gameVersion == "" || gameVersion == "latest" {
+			gameVersion = latestForgeGameVersion(promotions.Promos)
+			if gameVersion == "" {
+				return nil, fmt.Errorf("forge promotions list no versions")
+			}
+		}
+		if build == "" {
+			build = promotions.Promos[gameVersion+"-recommended"]
+			if build == "" {
+				build = promotions.Promos[gameVersion+"-latest"]
+			}
+			if build == "" {
+				return nil, fmt.Errorf("no forge build found for %s", gameVersion)
+			}
+		}
+	}
+
+	maven := strings.TrimRight(f.MavenURL, "/")
+	if maven == "" {
+		maven = DefaultForgeMavenURL
+	}
+	full := gameVersion + "-" + build
+	installerURL := fmt.Sprintf("%s/net/minecraftforge/forge/%s/forge-%s-installer.jar", maven, full, full)
+
+	inst := &Installation{Software: f.Name(), Version: gameVersion, Build: build}
+	return inst, runInstaller(ctx, req, inst, installerURL, "forge-"+full)
+}
+
+// latestForgeGameVersion returns the newest game version with a promotion.
+func latestForgeGameVersion(promos map[string]string) string {
+	latest := ""
+	for key := range promos {
+		i := strings.LastIndex(key, "-")
+		if i <= 0 {
+			continue
+		}
+		version := key[:i]
+		if latest == "" || compareVersionNumbers(version, latest) > 0 {
+			latest = version
+		}
+	}
+	return latest
+}
+
+// NeoForge installs NeoForge by running its installer with --installServer.
+// Only game versions from 1.20.2, which use NeoForge's own version numbers,
+// are supported.
+type NeoForge struct {
+	MavenURL string // maven root, DefaultNeoForgeMavenURL if empty
+	Version  string // NeoForge version, e.g. "21.1.77"; the newest for the game version if empty
+}
+
+type neoForgeVersions struct {
+	Versions []string `json:"versions"`
+}
+
+// Name implements Provider.
+func (n *NeoForge) Name() string {
+	return "neoforge"
+}
+
+// Install implements Provider.
+func (n *NeoForge) Install(ctx context.Context, req InstallRequest) (*Installation, error) {
+	maven := strings.TrimRight(n.MavenURL, "/")
+	if maven == "" {
+		maven = DefaultNeoForgeMavenURL
+	}
+
+	gameVersion, build := req.Version, n.Version
+	if build == "" {
+		listURL := maven + "/api/maven/versions/releases/net/neoforged/neoforge"
+		list, err := getJSON[neoForgeVersions](ctx, req.downloader(), listURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list neoforge versions: %w", err)
+		}
+		build = pickNeoForgeVersion(list.Versions, gameVersion)
+		if build == "" {
+			return nil, fmt.Errorf("no neoforge version found for %s", gameVersion)
+		}
+	}
+	if gameVersion == "" || gameVersion == "latest" {
+		gameVersion = neoForgeGameVersion(build)
+	}
+
+	installerURL := fmt.Sprintf("%s/net/neoforged/neoforge/%s/neoforge-%s-installer.jar", maven, build, build)
+	inst := &Installation{Software: n.Name(), Version: gameVersion, Build: build}
+	return inst, runInstaller(ctx, req, inst, installerURL, "neoforge-"+build)
+}
+
+// pickNeoForgeVersion returns the newest NeoForge version for a game version,
+// preferring stable releases over betas. NeoForge 21.1.x targets 1.21.1.
+func pickNeoForgeVersion(versions []string, gameVersion string) string {
+	prefix := ""
+	if gameVersion != "" && gameVersion != "latest" {
+		parts := strings.Split(gameVersion, ".")
+		if len(parts) < 2 || parts[0] != "1" {
+			return ""
+		}
+		patch := "0"
+		if len(parts) > 2 {
+			patch = parts[2]
+		}
+		prefix = parts[1] + "." + patch + "."
+	}
+
+	var candidates []string
+	for _, v := range versions {
+		if strings.HasPrefix(v, prefix) {
+			candidates = append(candidates, v)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return compareVersionNumbers(candidates[i], candidates[j]) < 0
+	})
+	for i := len(candidates) - 1; i >= 0; i-- {
+		if !strings.Contains(candidates[i], "-") {
+			return candidates[i]
+		}
+	}
+	if len(candidates) > 0 {
+		return candidates[len(candidates)-1]
+	}
+	return ""
+}
+
+// neoForgeGameVersion derives the game version a NeoForge version targets.
+func neoForgeGameVersion(build string) string {
+	parts := strings.Split(build, ".")
+	if len(parts) < 2 {
+		return ""
+	}
+	if parts[1] == "0" {
+		return "1." + parts[0]
+	}
+	return "1." + parts[0] + "." + parts[1]
+}
+
+// runInstaller downloads and runs a Forge-style installer in the server
+// directory and fills in how to launch the result. An existing installation of
+// the same build is reused.
+func runInstaller(ctx context.Context, req InstallRequest, inst *Installation, installerURL, jarPrefix string) error {
+	if prev, err := ReadInstallation(req.Directory); err == nil &&
+		prev.Software == inst.Software && prev.Version == inst.Version && prev.Build == inst.Build &&
+		launchTargetExists(req.Directory, prev) {
+		*inst = *prev
+		return nil
+	}
+
+	inst.JavaVersion = JavaVersionFor(inst.Version)
+	javaPath := "java"
+	if req.Java != nil {
+		path, err := req.Java(inst.JavaVersion)
+		if err != nil {
+			return fmt.Errorf("no java to run the %s installer: %w", inst.Software, err)
+		}
+		javaPath = path
+	}
+
+	if err := os.MkdirAll(req.Directory, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create output directory '%s': %w", req.Directory, err)
+	}
+	installer := filepath.Join(req.Directory, inst.Software+"-installer.jar")
+	if err := req.downloader().DownloadFile(ctx, installerURL, installer, ""); err != nil {
+		return fmt.Errorf("failed to download %s installer: %w", inst.Software, err)
+	}
+	defer os.Remove(installer)
+
+	cmd := exec.CommandContext(ctx, javaPath, "-jar", filepath.Base(installer), "--installServer")
+	cmd.Dir = req.Directory
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s installer failed: %w\n%s", inst.Software, err, lastLines(string(output), 10))
+	}
+
+	if err := detectLaunch(req.Directory, inst, jarPrefix); err != nil {
+		return err
+	}
+	return nil
+}
+
+// detectLaunch reads the run script written by modern installers to find the
+// JVM argument file or jar it starts. Installers for releases before 1.17 write
+// no script and leave a forge jar in the directory instead. The script's
+// user_jvm_args.txt is ignored in favour of the configured JVM options.
+func detectLaunch(directory string, inst *Installation, jarPrefix string) error {
+	script := "run.sh"
+	if runtime.GOOS == "windows" {
+		script = "run.bat"
+	}
+	if f, err := os.Open(filepath.Join(directory, script)); err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) == 0 || fields[0] != "java" {
+				continue
+			}
+			for i, field := range fields {
+				switch {
+				case field == "-jar" && i+1 < len(fields):
+					inst.Jar = fields[i+1]
+					return nil
+				case strings.HasPrefix(field, "@") && !strings.HasSuffix(field, "user_jvm_args.txt"):
+					inst.ArgsFile = filepath.FromSlash(strings.TrimPrefix(field, "@"))
+					return nil
+				}
+			}
+		}
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(directory, jarPrefix+"*.jar"))
+	for _, match := range matches {
+		if !strings.HasSuffix(match, "-installer.jar") {
+			inst.Jar = filepath.Base(match)
+			return nil
+		}
+	}
+	return fmt.Errorf("%s installer did not produce a launchable server", inst.Software)
+}
+
+func launchTargetExists(directory string, inst *Installation) bool {
+	target := inst.Jar
+	if inst.ArgsFile != "" {
+		target = inst.ArgsFile
+	}
+	if target == "" {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(directory, target))
+	return err == nil
+}
+
+// compareVersionNumbers compares dotted version numbers numerically, ignoring
+// any suffix after a '-'.
+func compareVersionNumbers(a, b string) int {
+	as := strings.Split(strings.SplitN(a, "-", 2)[0], ".")
+	bs := strings.Split(strings.SplitN(b, "-", 2)[0], ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
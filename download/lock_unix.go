@@ -0,0 +1,22 @@
+//go:build !windows
+
+package download
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile blocks until f is exclusively locked.
+func lockFile(f *os.File) error {
+	for {
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+		if err != syscall.EINTR {
+			return err
+		}
+	}
+}
+
+func unlockFile(f *os.File) {
+	_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
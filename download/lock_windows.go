@@ -0,0 +1,19 @@
+//go:build windows
+
+package download
+
+import (
+	"golang.org/x/sys/windows"
+	"os"
+)
+
+// lockFile blocks until f is exclusively locked.
+func lockFile(f *os.File) error {
+	overlapped := &windows.Overlapped{}
+	return windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK, 0, 1, 0, overlapped)
+}
+
+func unlockFile(f *os.File) {
+	overlapped := &windows.Overlapped{}
+	_ = windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, overlapped)
+}
@@ -0,0 +1,178 @@
+package download
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/xDefyingGravity/gomcserver/types"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultManifestMaxAge is how long a cached version manifest is used without
+// asking the server whether it changed.
+const DefaultManifestMaxAge = time.Hour
+
+// manifestFailureBackoff is how long a cached manifest is used without another
+// attempt after revalidating it failed, so that an offline host does not wait
+// for the network on every start.
+const manifestFailureBackoff = 5 * time.Minute
+
+// manifestMeta records how the cached manifest was fetched, for conditional
+// requests.
+type manifestMeta struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"lastModified,omitempty"`
+	FetchedAt    time.Time `json:"fetchedAt"`
+	FailedAt     time.Time `json:"failedAt,omitzero"` // last failed revalidation
+}
+
+// LoadManifest returns the version manifest, using the copy cached in
+// cacheDirectory while it is younger than maxAge (DefaultManifestMaxAge if 0).
+// An older copy is revalidated with a conditional request. If the manifest
+// cannot be fetched, the cached copy is returned regardless of its age so that
+// servers can start offline, and it is used without asking again for the next
+// few minutes.
+func LoadManifest(ctx context.Context, cacheDirectory string, maxAge time.Duration) (*types.VersionManifest, error) {
+	return loadManifest(ctx, DefaultDownloader, ManifestUrl, cacheDirectory, maxAge, false)
+}
+
+// RefreshManifest revalidates the cached version manifest regardless of its
+// age and returns the current one. Unlike LoadManifest it fails if the
+// manifest cannot be fetched.
+func RefreshManifest(ctx context.Context, cacheDirectory string) (*types.VersionManifest, error) {
+	return loadManifest(ctx, DefaultDownloader, ManifestUrl, cacheDirectory, 0, true)
+}
+
+func loadManifest(ctx context.Context, d *Downloader, manifestURL, cacheDirectory string, maxAge time.Duration, refresh bool) (*types.VersionManifest, error) {
+	if maxAge == 0 {
+		maxAge = DefaultManifestMaxAge
+	}
+	cacheDirectory = expandHomeDirectory(cacheDirectory)
+	manifestPath := filepath.Join(cacheDirectory, "manifest.json")
+	metaPath := filepath.Join(cacheDirectory, "manifest.meta.json")
+
+	var cached *types.VersionManifest
+	var meta manifestMeta
+	if err := loadJSONFile(manifestPath, &cached); err != nil {
+		cached = nil
+	} else if err := loadJSONFile(metaPath, &meta); err != nil || meta.URL != manifestURL {
+		// A manifest from another URL, or from before metadata was kept, is only
+		// good as an offline fallback.
+		meta = manifestMeta{URL: manifestURL}
+	}
+	if cached != nil && !refresh {
+		if !meta.FetchedAt.IsZero() && time.Since(meta.FetchedAt) < maxAge {
+			return cached, nil
+		}
+		if time.Since(meta.FailedAt) < min(manifestFailureBackoff, maxAge) {
+			return cached, nil
+		}
+	}
+
+	manifest, err := fetchManifest(ctx, d, manifestURL, manifestPath, &meta, cached != nil)
+	if err != nil {
+		if cached != nil && !refresh && ctx.Err() == nil {
+			meta.URL = manifestURL
+			meta.FailedAt = time.Now()
+			_ = writeJSONFile(metaPath, meta)
+			return cached, nil
+		}
+		return nil, err
+	}
+	if manifest == nil {
+		manifest = cached
+	}
+
+	meta.URL = manifestURL
+	meta.FetchedAt = time.Now()
+	meta.FailedAt = time.Time{}
+	if err := writeJSONFile(metaPath, meta); err != nil {
+		return nil, fmt.Errorf("failed to write manifest metadata: %w", err)
+	}
+	return manifest, nil
+}
+
+// fetchManifest downloads the manifest into manifestPath, sending the
+// validators in meta when conditional is set. It returns nil if the cached
+// copy is still current and updates meta from the response. It makes a single
+// attempt so that the offline fallback is not delayed by retries.
+func fetchManifest(ctx context.Context, d *Downloader, manifestURL, manifestPath string, meta *manifestMeta, conditional bool) (*types.VersionManifest, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, manifestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if conditional {
+		if meta.ETag != "" {
+			req.Header.Set("If-None-Match", meta.ETag)
+		}
+		if meta.LastModified != "" {
+			req.Header.Set("If-Modified-Since", meta.LastModified)
+		}
+	}
+	resp, err := d.client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download manifest: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && conditional {
+		return nil, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("failed to download manifest: %w", &HTTPError{URL: manifestURL, StatusCode: resp.StatusCode, Status: resp.Status})
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download manifest: %w", err)
+	}
+	var manifest types.VersionManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest JSON: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(manifestPath), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	if err := writeFileAtomic(manifestPath, data); err != nil {
+		return nil, fmt.Errorf("failed to cache manifest: %w", err)
+	}
+	meta.ETag = resp.Header.Get("ETag")
+	meta.LastModified = resp.Header.Get("Last-Modified")
+	return &manifest, nil
+}
+
+func writeJSONFile(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data)
+}
+
+// writeFileAtomic replaces path with data so that concurrent readers never see
+// a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
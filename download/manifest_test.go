@@ -0,0 +1,203 @@
+package download
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	manifestV1 = `{"latest":{"release":"1.21.1","snapshot":"24w33a"},"versions":[{"id":"24w33a","type":"snapshot"},{"id":"1.21.1","type":"release"}]}`
+	manifestV2 = `{"latest":{"release":"1.21.2","snapshot":"1.21.2"},"versions":[{"id":"1.21.2","type":"release"},{"id":"1.21.1","type":"release"}]}`
+)
+
+// manifestServer serves a manifest with an ETag, answering conditional
+// requests with 304 while it is unchanged. status overrides every response.
+type manifestServer struct {
+	*httptest.Server
+	mu          sync.Mutex
+	body, etag  string
+	status      int
+	requests    int
+	ifNoneMatch string
+}
+
+func newManifestServer(t *testing.T) *manifestServer {
+	t.Helper()
+	m := &manifestServer{body: manifestV1, etag: `"v1"`}
+	m.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.requests++
+		m.ifNoneMatch = r.Header.Get("If-None-Match")
+		if m.status != 0 {
+			w.WriteHeader(m.status)
+			return
+		}
+		w.Header().Set("ETag", m.etag)
+		w.Header().Set("Last-Modified", "Wed, 21 Oct 2015 07:28:00 GMT")
+		if m.ifNoneMatch == m.etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		_, _ = w.Write([]byte(m.body))
+	}))
+	t.Cleanup(m.Close)
+	return m
+}
+
+func (m *manifestServer) set(f func(m *manifestServer)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f(m)
+}
+
+func (m *manifestServer) stats() (int, string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.requests, m.ifNoneMatch
+}
+
+func (m *manifestServer) load(t *testing.T, dir string, maxAge time.Duration, refresh bool) (string, error) {
+	t.Helper()
+	d := &Downloader{Client: m.Client(), Retries: -1}
+	manifest, err := loadManifest(context.Background(), d, m.URL+"/manifest.json", dir, maxAge, refresh)
+	if err != nil {
+		return "", err
+	}
+	return manifest.Latest.Release, nil
+}
+
+func readMeta(t *testing.T, dir string) manifestMeta {
+	t.Helper()
+	var meta manifestMeta
+	if err := loadJSONFile(filepath.Join(dir, "manifest.meta.json"), &meta); err != nil {
+		t.Fatal(err)
+	}
+	return meta
+}
+
+// ageMeta makes the cached manifest look as if it was fetched age ago.
+func ageMeta(t *testing.T, dir string, age time.Duration) {
+	t.Helper()
+	meta := readMeta(t, dir)
+	meta.FetchedAt = time.Now().Add(-age)
+	if err := writeJSONFile(filepath.Join(dir, "manifest.meta.json"), meta); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestManifestFreshCacheHit(t *testing.T) {
+	srv := newManifestServer(t)
+	dir := t.TempDir()
+	for i := 0; i < 2; i++ {
+		if release, err := srv.load(t, dir, time.Hour, false); err != nil || release != "1.21.1" {
+			t.Fatalf("load %d = %q, %v", i, release, err)
+		}
+	}
+	if n, _ := srv.stats(); n != 1 {
+		t.Errorf("requests = %d, want 1", n)
+	}
+}
+
+func TestManifestRevalidationNotModified(t *testing.T) {
+	srv := newManifestServer(t)
+	dir := t.TempDir()
+	if _, err := srv.load(t, dir, time.Hour, false); err != nil {
+		t.Fatal(err)
+	}
+	ageMeta(t, dir, 2*time.Hour)
+	before := time.Now()
+
+	release, err := srv.load(t, dir, time.Hour, false)
+	if err != nil || release != "1.21.1" {
+		t.Fatalf("load = %q, %v", release, err)
+	}
+	n, ifNoneMatch := srv.stats()
+	if n != 2 || ifNoneMatch != `"v1"` {
+		t.Errorf("requests = %d with If-None-Match %q, want 2 with \"v1\"", n, ifNoneMatch)
+	}
+	meta := readMeta(t, dir)
+	if meta.FetchedAt.Before(before) || meta.ETag != `"v1"` || meta.LastModified == "" {
+		t.Errorf("metadata after 304 = %+v, want a refreshed FetchedAt and kept validators", meta)
+	}
+}
+
+func TestManifestRevalidationReplaced(t *testing.T) {
+	srv := newManifestServer(t)
+	dir := t.TempDir()
+	if _, err := srv.load(t, dir, time.Hour, false); err != nil {
+		t.Fatal(err)
+	}
+	srv.set(func(m *manifestServer) { m.body, m.etag = manifestV2, `"v2"` })
+
+	// Refresh ignores the age of the cached copy.
+	release, err := srv.load(t, dir, time.Hour, true)
+	if err != nil || release != "1.21.2" {
+		t.Fatalf("refresh = %q, %v; want 1.21.2", release, err)
+	}
+	if meta := readMeta(t, dir); meta.ETag != `"v2"` {
+		t.Errorf("ETag = %q, want \"v2\"", meta.ETag)
+	}
+	if release, err := srv.load(t, dir, time.Hour, false); err != nil || release != "1.21.2" {
+		t.Fatalf("cached load = %q, %v; want 1.21.2", release, err)
+	}
+}
+
+func TestManifestServerErrorFallsBackToCache(t *testing.T) {
+	srv := newManifestServer(t)
+	dir := t.TempDir()
+	if _, err := srv.load(t, dir, time.Hour, false); err != nil {
+		t.Fatal(err)
+	}
+	ageMeta(t, dir, 2*time.Hour)
+	srv.set(func(m *manifestServer) { m.status = http.StatusInternalServerError })
+
+	release, err := srv.load(t, dir, time.Hour, false)
+	if err != nil || release != "1.21.1" {
+		t.Fatalf("load = %q, %v; want the cached 1.21.1", release, err)
+	}
+	if meta := readMeta(t, dir); meta.FailedAt.IsZero() {
+		t.Error("failed revalidation was not recorded")
+	}
+
+	// The failure is remembered, so the next load does not ask again.
+	if _, err := srv.load(t, dir, time.Hour, false); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := srv.stats(); n != 2 {
+		t.Errorf("requests = %d, want 2", n)
+	}
+
+	// Refresh always asks and reports the failure.
+	if _, err := srv.load(t, dir, time.Hour, true); err == nil {
+		t.Error("refresh succeeded while the server fails")
+	}
+
+	// Once the server recovers and the backoff has passed, the cache is
+	// revalidated again and the failure is cleared.
+	srv.set(func(m *manifestServer) { m.status = 0 })
+	meta := readMeta(t, dir)
+	meta.FailedAt = time.Now().Add(-time.Hour)
+	if err := writeJSONFile(filepath.Join(dir, "manifest.meta.json"), meta); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := srv.load(t, dir, time.Hour, false); err != nil {
+		t.Fatal(err)
+	}
+	if meta := readMeta(t, dir); !meta.FailedAt.IsZero() {
+		t.Errorf("FailedAt = %v after a successful revalidation, want zero", meta.FailedAt)
+	}
+}
+
+func TestManifestServerErrorWithoutCache(t *testing.T) {
+	srv := newManifestServer(t)
+	srv.set(func(m *manifestServer) { m.status = http.StatusServiceUnavailable })
+	if _, err := srv.load(t, t.TempDir(), time.Hour, false); err == nil {
+		t.Fatal("load succeeded without a cache or a server")
+	}
+}
@@ -0,0 +1,129 @@
+package download
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultPaperURL is the PaperMC API used when Paper.BaseURL is empty.
+const DefaultPaperURL = "https://api.papermc.io"
+
+// Paper installs a server from the PaperMC build API. Set Project to "folia"
+// for Folia.
+type Paper struct {
+	BaseURL string // API root, DefaultPaperURL if empty
+	Project string // "paper" if empty
+	Build   int    // build to install, the latest stable build if 0
+}
+
+type paperProject struct {
+	Versions []string `json:"versions"`
+}
+
+type paperBuilds struct {
+	Builds []paperBuild `json:"builds"`
+}
+
+type paperBuild struct {
+	Build     int    `json:"build"`
+	Channel   string `json:"channel"`
+	Downloads struct {
+		Application struct {
+			Name   string `json:"name"`
+			Sha256 string `json:"sha256"`
+		} `json:"application"`
+	} `json:"downloads"`
+}
+
+// Name implements Provider.
+func (p *Paper) Name() string {
+	return p.project()
+}
+
+func (p *Paper) project() string {
+	if p.Project == "" {
+		return "paper"
+	}
+	return p.Project
+}
+
+// Install implements Provider.
+func (p *Paper) Install(ctx context.Context, req InstallRequest) (*Installation, error) {
+	base := strings.TrimRight(p.BaseURL, "/")
+	if base == "" {
+		base = DefaultPaperURL
+	}
+	projectURL := base + "/v2/projects/" + p.project()
+
+	version := req.Version
+	if version == "" || version == "latest" {
+		project, err := getJSON[paperProject](ctx, req.downloader(), projectURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s versions: %w", p.project(), err)
+		}
+		version = latestRelease(project.Versions)
+		if version == "" {
+			return nil, fmt.Errorf("%s has no versions", p.project())
+		}
+	}
+
+	versionURL := projectURL + "/versions/" + version
+	builds, err := getJSON[paperBuilds](ctx, req.downloader(), versionURL+"/builds")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s builds for %s: %w", p.project(), version, err)
+	}
+	build, err := p.pickBuild(builds.Builds, version)
+	if err != nil {
+		return nil, err
+	}
+
+	name := build.Downloads.Application.Name
+	jarURL := fmt.Sprintf("%s/builds/%d/downloads/%s", versionURL, build.Build, name)
+	jarPath := filepath.Join(req.Directory, "server.jar")
+	if err := fetchVerified(ctx, req.downloader(), req.Jars, jarURL, sha256.New, "sha256", build.Downloads.Application.Sha256, jarPath); err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", name, err)
+	}
+	return &Installation{
+		Software: p.project(),
+		Version:  version,
+		Build:    strconv.Itoa(build.Build),
+		Jar:      "server.jar",
+	}, nil
+}
+
+// pickBuild returns the requested build, or the newest build on the default
+// (stable) channel, falling back to the newest build of any channel.
+func (p *Paper) pickBuild(builds []paperBuild, version string) (*paperBuild, error) {
+	if len(builds) == 0 {
+		return nil, fmt.Errorf("%s has no builds for %s", p.project(), version)
+	}
+	if p.Build != 0 {
+		for i := range builds {
+			if builds[i].Build == p.Build {
+				return &builds[i], nil
+			}
+		}
+		return nil, fmt.Errorf("%s build %d not found for %s", p.project(), p.Build, version)
+	}
+	for i := len(builds) - 1; i >= 0; i-- {
+		if builds[i].Channel == "" || builds[i].Channel == "default" || builds[i].Channel == "STABLE" {
+			return &builds[i], nil
+		}
+	}
+	return &builds[len(builds)-1], nil
+}
+
+// latestRelease returns the last entry of an ascending version list that is
+// not a pre-release.
+func latestRelease(versions []string) string {
+	for i := len(versions) - 1; i >= 0; i-- {
+		if !strings.Contains(versions[i], "-") {
+			return versions[i]
+		}
+	}
+	return ""
+}
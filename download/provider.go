@@ -0,0 +1,125 @@
+package download
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Provider installs a server distribution, such as vanilla, Paper or Forge,
+// into a server directory.
+type Provider interface {
+	// Name returns the name of the server software, e.g. "paper".
+	Name() string
+	// Install downloads the server for req.Version into req.Directory, running
+	// an installer if the distribution needs one, and reports how to launch it.
+	Install(ctx context.Context, req InstallRequest) (*Installation, error)
+}
+
+// InstallRequest describes what a Provider should install.
+type InstallRequest struct {
+	Version   string // game version, or "latest" for the newest release
+	Directory string // server directory
+	UseCache  bool   // cache version manifests in CacheDir
+	CacheDir  string
+	// ManifestMaxAge is how long a cached manifest is used before it is
+	// revalidated, DefaultManifestMaxAge if 0.
+	ManifestMaxAge time.Duration
+	// Jars shares downloaded jars between server directories; nil downloads
+	// straight into Directory.
+	Jars *JarCache
+	// Downloader fetches files, DefaultDownloader if nil.
+	Downloader *Downloader
+	// Java returns a java executable able to run the given major version (0 if
+	// unknown). Providers that run an installer use it.
+	Java func(required int) (string, error)
+}
+
+func (r *InstallRequest) downloader() *Downloader {
+	if r.Downloader != nil {
+		return r.Downloader
+	}
+	return DefaultDownloader
+}
+
+// Installation describes an installed server.
+type Installation struct {
+	Software    string `json:"software"`
+	Version     string `json:"version"`            // resolved game version
+	Build       string `json:"build,omitempty"`    // distribution build or loader version
+	Jar         string `json:"jar,omitempty"`      // jar to run with -jar, relative to the directory
+	ArgsFile    string `json:"argsFile,omitempty"` // JVM argument file to run instead of Jar
+	JavaVersion int    `json:"javaVersion,omitempty"`
+}
+
+// LaunchArgs returns the arguments that start the server, following the JVM
+// options.
+func (i *Installation) LaunchArgs() []string {
+	if i.ArgsFile != "" {
+		return []string{"@" + filepath.ToSlash(i.ArgsFile)}
+	}
+	return []string{"-jar", i.Jar}
+}
+
+// InstallationPath returns where the installation of a server directory is
+// recorded.
+func InstallationPath(serverDirectory string) string {
+	return filepath.Join(serverDirectory, ".mcserverlib", "install.json")
+}
+
+// WriteInstallation records inst for the server directory.
+func WriteInstallation(serverDirectory string, inst *Installation) error {
+	data, err := json.MarshalIndent(inst, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := InstallationPath(serverDirectory)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create mcserverlib directory: %w", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// ReadInstallation loads the installation recorded for the server directory.
+func ReadInstallation(serverDirectory string) (*Installation, error) {
+	var inst *Installation
+	if err := loadJSONFile(InstallationPath(serverDirectory), &inst); err != nil {
+		return nil, err
+	}
+	return inst, nil
+}
+
+// JavaVersionFor returns the Java version Mojang requires for a release, or 0
+// if the version is not a release number. Distributions built on vanilla share
+// its requirement.
+func JavaVersionFor(gameVersion string) int {
+	parts := strings.Split(gameVersion, ".")
+	if len(parts) < 2 || parts[0] != "1" {
+		return 0
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0
+	}
+	patch := 0
+	if len(parts) > 2 {
+		if patch, err = strconv.Atoi(parts[2]); err != nil {
+			return 0
+		}
+	}
+	switch {
+	case minor < 17:
+		return 8
+	case minor == 17:
+		return 16
+	case minor < 20 || (minor == 20 && patch < 5):
+		return 17
+	default:
+		return 21
+	}
+}
@@ -0,0 +1,250 @@
+package download
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeAPI serves fixed responses by request path and records the paths it was
+// asked for.
+type fakeAPI struct {
+	*httptest.Server
+	mu        sync.Mutex
+	responses map[string]string
+	requested []string
+}
+
+func newFakeAPI(t *testing.T, responses map[string]string) *fakeAPI {
+	t.Helper()
+	api := &fakeAPI{responses: responses}
+	api.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		api.mu.Lock()
+		api.requested = append(api.requested, r.URL.Path)
+		body, ok := api.responses[r.URL.Path]
+		api.mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(api.Close)
+	return api
+}
+
+func (a *fakeAPI) fetched(path string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, p := range a.requested {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *fakeAPI) request(t *testing.T, version string) InstallRequest {
+	return InstallRequest{
+		Version:    version,
+		Directory:  t.TempDir(),
+		Downloader: &Downloader{Client: a.Client(), Retries: -1},
+	}
+}
+
+func hexDigest(sum []byte) string {
+	return hex.EncodeToString(sum)
+}
+
+func assertFile(t *testing.T, path, want string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	if string(data) != want {
+		t.Errorf("%s = %q, want %q", filepath.Base(path), data, want)
+	}
+}
+
+func TestPaperResolvesLatestStableBuild(t *testing.T) {
+	jar := "paper jar"
+	sum := sha256.Sum256([]byte(jar))
+	api := newFakeAPI(t, map[string]string{
+		"/v2/projects/paper": `{"versions":["1.20.4","1.21.1","1.21.2-rc1"]}`,
+		"/v2/projects/paper/versions/1.21.1/builds": `{"builds":[
+			{"build":100,"channel":"default","downloads":{"application":{"name":"paper-1.21.1-100.jar","sha256":"` + hexDigest(sum[:]) + `"}}},
+			{"build":101,"channel":"experimental","downloads":{"application":{"name":"paper-1.21.1-101.jar","sha256":"00"}}}
+		]}`,
+		"/v2/projects/paper/versions/1.21.1/builds/100/downloads/paper-1.21.1-100.jar": jar,
+	})
+
+	req := api.request(t, "latest")
+	inst, err := (&Paper{BaseURL: api.URL}).Install(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Install: %v", err)
+	}
+	if inst.Software != "paper" || inst.Version != "1.21.1" || inst.Build != "100" || inst.Jar != "server.jar" {
+		t.Errorf("installation = %+v, want paper 1.21.1 build 100", inst)
+	}
+	assertFile(t, filepath.Join(req.Directory, "server.jar"), jar)
+}
+
+func TestPaperRejectsDigestMismatch(t *testing.T) {
+	api := newFakeAPI(t, map[string]string{
+		"/v2/projects/folia/versions/1.21.1/builds": `{"builds":[
+			{"build":7,"channel":"default","downloads":{"application":{"name":"folia.jar","sha256":"00"}}}
+		]}`,
+		"/v2/projects/folia/versions/1.21.1/builds/7/downloads/folia.jar": "tampered",
+	})
+	_, err := (&Paper{BaseURL: api.URL, Project: "folia"}).Install(context.Background(), api.request(t, "1.21.1"))
+	if err == nil {
+		t.Fatal("Install succeeded with a mismatched digest")
+	}
+}
+
+func TestPurpurResolvesLatestBuild(t *testing.T) {
+	jar := "purpur jar"
+	sum := md5.Sum([]byte(jar))
+	api := newFakeAPI(t, map[string]string{
+		"/v2/purpur":                      `{"versions":["1.20.6","1.21.1"]}`,
+		"/v2/purpur/1.21.1":               `{"builds":{"latest":"2329","all":["2328","2329"]}}`,
+		"/v2/purpur/1.21.1/2329":          `{"build":"2329","md5":"` + hexDigest(sum[:]) + `"}`,
+		"/v2/purpur/1.21.1/2329/download": jar,
+	})
+
+	req := api.request(t, "")
+	inst, err := (&Purpur{BaseURL: api.URL}).Install(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Install: %v", err)
+	}
+	if inst.Software != "purpur" || inst.Version != "1.21.1" || inst.Build != "2329" {
+		t.Errorf("installation = %+v, want purpur 1.21.1 build 2329", inst)
+	}
+	assertFile(t, filepath.Join(req.Directory, "server.jar"), jar)
+}
+
+func TestFabricResolvesStableComponents(t *testing.T) {
+	api := newFakeAPI(t, map[string]string{
+		"/v2/versions/game":                                  `[{"version":"1.21.2-rc1","stable":false},{"version":"1.21.1","stable":true}]`,
+		"/v2/versions/loader":                                `[{"version":"0.16.6","stable":false},{"version":"0.16.5","stable":true}]`,
+		"/v2/versions/installer":                             `[{"version":"1.0.1","stable":true}]`,
+		"/v2/versions/loader/1.21.1/0.16.5/1.0.1/server/jar": "fabric launcher",
+	})
+
+	req := api.request(t, "latest")
+	inst, err := (&Fabric{BaseURL: api.URL}).Install(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Install: %v", err)
+	}
+	if inst.Version != "1.21.1" || inst.Build != "0.16.5" || inst.Jar != fabricLauncherJar {
+		t.Errorf("installation = %+v, want fabric 1.21.1 with loader 0.16.5", inst)
+	}
+	assertFile(t, filepath.Join(req.Directory, fabricLauncherJar), "fabric launcher")
+}
+
+// fakeInstaller returns a java executable that behaves like a Forge-style
+// installer run with --installServer: it writes a run script that starts the
+// server from a JVM argument file.
+func fakeInstaller(t *testing.T) (java func(int) (string, error), required *int) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake installer is a shell script")
+	}
+	script := filepath.Join(t.TempDir(), "java")
+	body := `#!/bin/sh
+[ "$1" = "-jar" ] && [ -f "$2" ] && [ "$3" = "--installServer" ] || exit 1
+mkdir -p libraries
+touch libraries/unix_args.txt
+echo 'java @user_jvm_args.txt @libraries/unix_args.txt "$@"' > run.sh
+`
+	if err := os.WriteFile(script, []byte(body), 0755); err != nil {
+		t.Fatal(err)
+	}
+	required = new(int)
+	return func(major int) (string, error) {
+		*required = major
+		return script, nil
+	}, required
+}
+
+func TestForgeResolvesPromotedBuild(t *testing.T) {
+	installer := "/maven/net/minecraftforge/forge/1.21.1-52.0.1/forge-1.21.1-52.0.1-installer.jar"
+	api := newFakeAPI(t, map[string]string{
+		"/promotions_slim.json": `{"promos":{"1.20.1-recommended":"47.2.0","1.20.1-latest":"47.3.0","1.21.1-latest":"52.0.1"}}`,
+		installer:               "installer",
+		"/maven/net/minecraftforge/forge/1.20.1-47.2.0/forge-1.20.1-47.2.0-installer.jar": "installer",
+	})
+	java, required := fakeInstaller(t)
+
+	req := api.request(t, "latest")
+	req.Java = java
+	forge := &Forge{MavenURL: api.URL + "/maven", PromotionsURL: api.URL + "/promotions_slim.json"}
+	inst, err := forge.Install(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Install: %v", err)
+	}
+	if inst.Version != "1.21.1" || inst.Build != "52.0.1" {
+		t.Errorf("installation = %+v, want forge 1.21.1-52.0.1", inst)
+	}
+	if !api.fetched(installer) {
+		t.Errorf("installer %s was not downloaded", installer)
+	}
+	if want := filepath.FromSlash("libraries/unix_args.txt"); inst.ArgsFile != want {
+		t.Errorf("ArgsFile = %q, want %q", inst.ArgsFile, want)
+	}
+	if *required != 21 || inst.JavaVersion != 21 {
+		t.Errorf("installer asked for Java %d, installation records %d; want 21", *required, inst.JavaVersion)
+	}
+	if _, err := os.Stat(filepath.Join(req.Directory, "forge-installer.jar")); !os.IsNotExist(err) {
+		t.Error("installer jar was left in the server directory")
+	}
+
+	// The recommended build wins over the latest one.
+	req = api.request(t, "1.20.1")
+	req.Java = java
+	if inst, err = forge.Install(context.Background(), req); err != nil {
+		t.Fatalf("Install 1.20.1: %v", err)
+	}
+	if inst.Build != "47.2.0" {
+		t.Errorf("Build = %q, want the recommended 47.2.0", inst.Build)
+	}
+}
+
+func TestNeoForgeResolvesVersionForGame(t *testing.T) {
+	installer := "/net/neoforged/neoforge/21.1.77/neoforge-21.1.77-installer.jar"
+	api := newFakeAPI(t, map[string]string{
+		"/api/maven/versions/releases/net/neoforged/neoforge": `{"versions":["20.4.237","21.0.5","21.1.70","21.1.77","21.1.80-beta"]}`,
+		installer: "installer",
+	})
+	java, _ := fakeInstaller(t)
+
+	for _, version := range []string{"1.21.1", "latest"} {
+		req := api.request(t, version)
+		req.Java = java
+		inst, err := (&NeoForge{MavenURL: api.URL}).Install(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Install %s: %v", version, err)
+		}
+		if inst.Version != "1.21.1" || inst.Build != "21.1.77" {
+			t.Errorf("Install %s = %+v, want neoforge 21.1.77 for 1.21.1", version, inst)
+		}
+	}
+	if !api.fetched(installer) {
+		t.Errorf("installer %s was not downloaded", installer)
+	}
+
+	_, err := (&NeoForge{MavenURL: api.URL}).Install(context.Background(), api.request(t, "1.19.2"))
+	if err == nil || !strings.Contains(err.Error(), "no neoforge version") {
+		t.Errorf("Install 1.19.2 error = %v, want no neoforge version", err)
+	}
+}
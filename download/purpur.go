@@ -0,0 +1,82 @@
+package download
+
+import (
+	"context"
+	"crypto/md5"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultPurpurURL is the Purpur API used when Purpur.BaseURL is empty.
+const DefaultPurpurURL = "https://api.purpurmc.org"
+
+// Purpur installs a server from the Purpur build API.
+type Purpur struct {
+	BaseURL string // API root, DefaultPurpurURL if empty
+	Build   string // build to install, the latest if empty
+}
+
+type purpurProject struct {
+	Versions []string `json:"versions"`
+}
+
+type purpurVersion struct {
+	Builds struct {
+		Latest string   `json:"latest"`
+		All    []string `json:"all"`
+	} `json:"builds"`
+}
+
+type purpurBuild struct {
+	Build string `json:"build"`
+	MD5   string `json:"md5"`
+}
+
+// Name implements Provider.
+func (p *Purpur) Name() string {
+	return "purpur"
+}
+
+// Install implements Provider.
+func (p *Purpur) Install(ctx context.Context, req InstallRequest) (*Installation, error) {
+	base := strings.TrimRight(p.BaseURL, "/")
+	if base == "" {
+		base = DefaultPurpurURL
+	}
+
+	version := req.Version
+	if version == "" || version == "latest" {
+		project, err := getJSON[purpurProject](ctx, req.downloader(), base+"/v2/purpur")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list purpur versions: %w", err)
+		}
+		version = latestRelease(project.Versions)
+		if version == "" {
+			return nil, fmt.Errorf("purpur has no versions")
+		}
+	}
+
+	build := p.Build
+	if build == "" {
+		info, err := getJSON[purpurVersion](ctx, req.downloader(), base+"/v2/purpur/"+version)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list purpur builds for %s: %w", version, err)
+		}
+		build = info.Builds.Latest
+		if build == "" {
+			return nil, fmt.Errorf("purpur has no builds for %s", version)
+		}
+	}
+
+	buildURL := base + "/v2/purpur/" + version + "/" + build
+	info, err := getJSON[purpurBuild](ctx, req.downloader(), buildURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up purpur build %s: %w", build, err)
+	}
+	jarPath := filepath.Join(req.Directory, "server.jar")
+	if err := fetchVerified(ctx, req.downloader(), req.Jars, buildURL+"/download", md5.New, "md5", info.MD5, jarPath); err != nil {
+		return nil, fmt.Errorf("failed to download purpur %s build %s: %w", version, build, err)
+	}
+	return &Installation{Software: p.Name(), Version: version, Build: build, Jar: "server.jar"}, nil
+}
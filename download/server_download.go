@@ -1,6 +1,8 @@
 package download
 
 import (
+	"context"
+	"crypto/sha1"
 	"encoding/json"
 	"fmt"
 	"github.com/xDefyingGravity/gomcserver/types"
@@ -8,6 +10,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"time"
 )
 
 func isURL(s string) bool {
@@ -41,86 +44,103 @@ func expandHomeDirectory(path string) string {
 // DownloadServerJar downloads the Minecraft server JAR file for the specified version.
 // It uses caching if enabled and saves the server JAR in the output directory.
 func DownloadServerJar(version, outputDirectory string, useCache bool, cacheDirectory string) (string, error) {
+	return DownloadServerJarContext(context.Background(), version, outputDirectory, useCache, cacheDirectory)
+}
+
+// DownloadServerJarContext is like DownloadServerJar but aborts any in-flight
+// download when ctx is done. useCache only caches the version manifest; jars
+// are shared through a JarCache by Vanilla with InstallRequest.Jars instead.
+func DownloadServerJarContext(ctx context.Context, version, outputDirectory string, useCache bool, cacheDirectory string) (string, error) {
+	jarPath, _, err := downloadServerJar(ctx, DefaultDownloader, ManifestUrl, version, outputDirectory, useCache, cacheDirectory, 0, nil)
+	return jarPath, err
+}
+
+// downloadServerJar downloads the server jar using the given manifest and
+// returns its path and the resolved version. A cached manifest is used for up
+// to maxAge, and the jar is shared through jars if it is not nil.
+func downloadServerJar(ctx context.Context, d *Downloader, manifestURL, version, outputDirectory string, useCache bool, cacheDirectory string, maxAge time.Duration, jars *JarCache) (string, string, error) {
 	cacheDirPath := expandHomeDirectory(cacheDirectory)
 	outputDirectory = filepath.Clean(outputDirectory)
 
 	// Ensure output directory exists
 	if err := os.MkdirAll(outputDirectory, os.ModePerm); err != nil {
-		return "", fmt.Errorf("failed to create output directory '%s': %w", outputDirectory, err)
+		return "", "", fmt.Errorf("failed to create output directory '%s': %w", outputDirectory, err)
 	}
 
 	if isURL(version) {
-		// If the version is a direct URL, download it directly
+		// If the version is a direct URL, download it directly. Version data
+		// left by an earlier vanilla jar no longer describes the server.
+		_ = os.Remove(VersionDataPath(outputDirectory))
 		jarPath := filepath.Join(outputDirectory, "server.jar")
-		if err := DownloadFile(version, jarPath, ""); err != nil {
-			return "", fmt.Errorf("failed to download server JAR from URL '%s': %w", version, err)
+		if err := d.DownloadFile(ctx, version, jarPath, ""); err != nil {
+			return "", "", fmt.Errorf("failed to download server JAR from URL '%s': %w", version, err)
 		}
 
-		return jarPath, nil
+		return jarPath, version, nil
 	} else {
 		var manifest *types.VersionManifest
 		if useCache {
-			manifestPath := filepath.Join(cacheDirPath, "manifest.json")
-			if err := os.MkdirAll(cacheDirPath, os.ModePerm); err != nil {
-				return "", fmt.Errorf("failed to create cache directory '%s': %w", cacheDirPath, err)
-			}
-			if err := DownloadFile(ManifestUrl, manifestPath, ""); err != nil {
-				return "", fmt.Errorf("failed to download manifest file: %w", err)
-			}
-			if err := loadJSONFile(manifestPath, &manifest); err != nil {
-				return "", fmt.Errorf("failed to parse manifest JSON: %w", err)
+			var err error
+			manifest, err = loadManifest(ctx, d, manifestURL, cacheDirPath, maxAge, false)
+			if err != nil {
+				return "", "", err
 			}
 		} else {
 			var err error
-			manifest, err = DownloadJSON[types.VersionManifest](ManifestUrl)
+			manifest, err = getJSON[types.VersionManifest](ctx, d, manifestURL)
 			if err != nil {
-				return "", fmt.Errorf("failed to download manifest JSON: %w", err)
+				return "", "", fmt.Errorf("failed to download manifest JSON: %w", err)
 			}
 		}
 
-		// Resolve the version
-		if version == "latest" {
-			version = manifest.Latest.Release
-		}
-		versionEntry, err := findVersion(manifest, version)
+		// Resolve the version, which may be an alias or a constraint
+		versionEntry, err := NewCatalogue(manifest).Resolve(version)
 		if err != nil {
-			return "", err
+			return "", "", err
 		}
+		version = versionEntry.ID
 
 		// Create mcserverlib directory
 		mcserverlibDir := filepath.Join(outputDirectory, ".mcserverlib")
 		if err := os.MkdirAll(mcserverlibDir, os.ModePerm); err != nil {
-			return "", fmt.Errorf("failed to create mcserverlib directory '%s': %w", mcserverlibDir, err)
+			return "", "", fmt.Errorf("failed to create mcserverlib directory '%s': %w", mcserverlibDir, err)
 		}
 
 		// Download and parse version data
-		versionDataPath := filepath.Join(mcserverlibDir, "data.json")
-		if err := DownloadFile(versionEntry.URL, versionDataPath, versionEntry.Sha1); err != nil {
-			return "", fmt.Errorf("failed to download version data file: %w", err)
+		versionDataPath := VersionDataPath(outputDirectory)
+		if err := fetchVerified(ctx, d, nil, versionEntry.URL, sha1.New, "sha1", versionEntry.Sha1, versionDataPath); err != nil {
+			return "", "", fmt.Errorf("failed to download version data file: %w", err)
 		}
 		var versionData *types.VersionData
 		if err := loadJSONFile(versionDataPath, &versionData); err != nil {
-			return "", fmt.Errorf("failed to parse version data JSON: %w", err)
+			return "", "", fmt.Errorf("failed to parse version data JSON: %w", err)
 		}
 
 		// Download the server JAR
 		jarPath := filepath.Join(outputDirectory, "server.jar")
-		if err := DownloadFile(versionData.Downloads.Server.URL, jarPath, versionData.Downloads.Server.Sha1); err != nil {
-			return "", fmt.Errorf("failed to download server JAR file: %w", err)
+		if err := fetchVerified(ctx, d, jars, versionData.Downloads.Server.URL, sha1.New, "sha1", versionData.Downloads.Server.Sha1, jarPath); err != nil {
+			return "", "", fmt.Errorf("failed to download server JAR file: %w", err)
 		}
 
-		return jarPath, nil
+		return jarPath, version, nil
 	}
 }
 
-// findVersion searches for a version in the manifest and returns it.
-func findVersion(manifest *types.VersionManifest, version string) (*types.Version, error) {
-	for _, v := range manifest.Versions {
-		if v.ID == version {
-			return &v, nil
-		}
+// VersionDataPath returns where DownloadServerJar stores the version data of
+// the server jar in a server directory.
+func VersionDataPath(serverDirectory string) string {
+	return filepath.Join(serverDirectory, ".mcserverlib", "data.json")
+}
+
+// ReadVersionData loads the version data stored by DownloadServerJar. It
+// returns an error wrapping os.ErrNotExist if the server jar did not come from
+// the version manifest.
+func ReadVersionData(serverDirectory string) (*types.VersionData, error) {
+	var data *types.VersionData
+	if err := loadJSONFile(VersionDataPath(serverDirectory), &data); err != nil {
+		return nil, err
 	}
-	return nil, fmt.Errorf("version '%s' not found in manifest", version)
+	return data, nil
 }
 
 // loadJSONFile reads and unmarshals a JSON file into the given target.
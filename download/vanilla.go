@@ -0,0 +1,35 @@
+package download
+
+import (
+	"context"
+	"path/filepath"
+)
+
+// Vanilla installs the official server from Mojang's version manifest. A
+// Version that is an http(s) URL is downloaded as the server jar instead.
+type Vanilla struct {
+	ManifestURL string // version manifest, ManifestUrl if empty
+}
+
+// Name implements Provider.
+func (v *Vanilla) Name() string {
+	return "vanilla"
+}
+
+// Install implements Provider.
+func (v *Vanilla) Install(ctx context.Context, req InstallRequest) (*Installation, error) {
+	manifestURL := v.ManifestURL
+	if manifestURL == "" {
+		manifestURL = ManifestUrl
+	}
+	jarPath, version, err := downloadServerJar(ctx, req.downloader(), manifestURL, req.Version, req.Directory, req.UseCache, req.CacheDir, req.ManifestMaxAge, req.Jars)
+	if err != nil {
+		return nil, err
+	}
+
+	inst := &Installation{Software: v.Name(), Version: version, Jar: filepath.Base(jarPath)}
+	if data, err := ReadVersionData(req.Directory); err == nil {
+		inst.JavaVersion = data.JavaVersion.MajorVersion
+	}
+	return inst, nil
+}
@@ -0,0 +1,457 @@
+package download
+
+import (
+	"fmt"
+	"github.com/xDefyingGravity/gomcserver/types"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version types listed in the version manifest.
+const (
+	VersionTypeRelease  = "release"
+	VersionTypeSnapshot = "snapshot"
+	VersionTypeOldBeta  = "old_beta"
+	VersionTypeOldAlpha = "old_alpha"
+)
+
+// VersionFilter selects versions from a Catalogue. Zero fields match every
+// version.
+type VersionFilter struct {
+	Types      []string  // version types to include, e.g. VersionTypeRelease
+	After      time.Time // only versions released at or after this time
+	Before     time.Time // only versions released before this time
+	Constraint string    // e.g. "1.20.x" or ">=1.19 <1.21"; see Catalogue.Resolve
+}
+
+// Catalogue lists, orders and resolves the versions of a version manifest.
+//
+// Versions are ordered the way they were developed: a weekly snapshot such as
+// 20w45a sorts with the release it led to (1.17, not the 1.16.5 hotfix released
+// in between), before its pre-releases and release candidates, which sort
+// before the release itself. Alpha and beta versions sort before 1.0.
+type Catalogue struct {
+	manifest *types.VersionManifest
+	versions []types.Version // newest first
+	keys     map[string]versionKey
+}
+
+// NewCatalogue indexes manifest.
+func NewCatalogue(manifest *types.VersionManifest) *Catalogue {
+	c := &Catalogue{
+		manifest: manifest,
+		versions: append([]types.Version(nil), manifest.Versions...),
+		keys:     make(map[string]versionKey, len(manifest.Versions)),
+	}
+	sort.SliceStable(c.versions, func(i, j int) bool {
+		return c.versions[i].ReleaseTime.Before(c.versions[j].ReleaseTime)
+	})
+
+	// A snapshot leads to the next release that opened a new major.minor line.
+	// Hotfixes such as 1.16.5, released while 1.17 was in development, take no
+	// snapshots, so walk from oldest to newest to find the releases that did.
+	opened := make(map[string]bool)
+	var line []int
+	for _, v := range c.versions {
+		key := parseVersionID(v.ID)
+		key.time = v.ReleaseTime
+		if key.era == eraModern && key.stage == stageRelease {
+			if l := key.release[:min(len(key.release), 2)]; compareNumbers(l, line) > 0 {
+				opened[v.ID] = true
+				line = l
+			}
+		}
+		c.keys[v.ID] = key
+	}
+
+	// Walk from newest to oldest so that every snapshot knows that release.
+	// Snapshots newer than the last of them keep no release number and sort
+	// after every release.
+	var next []int
+	for i := len(c.versions) - 1; i >= 0; i-- {
+		id := c.versions[i].ID
+		key := c.keys[id]
+		if key.era != eraModern {
+			continue
+		}
+		if opened[id] {
+			next = key.release
+		} else if key.stage != stageRelease && key.release == nil {
+			key.release = next
+			c.keys[id] = key
+		}
+	}
+
+	sort.SliceStable(c.versions, func(i, j int) bool {
+		return compareKeys(c.keys[c.versions[i].ID], c.keys[c.versions[j].ID]) > 0
+	})
+	return c
+}
+
+// Get returns the version with the given ID.
+func (c *Catalogue) Get(id string) (*types.Version, bool) {
+	for i := range c.versions {
+		if c.versions[i].ID == id {
+			v := c.versions[i]
+			return &v, true
+		}
+	}
+	return nil, false
+}
+
+// Versions returns the versions matching filter, newest first.
+func (c *Catalogue) Versions(filter VersionFilter) ([]types.Version, error) {
+	var match func(versionKey) bool
+	if filter.Constraint != "" {
+		var err error
+		if match, err = c.parseConstraint(filter.Constraint); err != nil {
+			return nil, err
+		}
+	}
+
+	var versions []types.Version
+	for _, v := range c.versions {
+		if len(filter.Types) > 0 && !containsString(filter.Types, v.Type) {
+			continue
+		}
+		if !filter.After.IsZero() && v.ReleaseTime.Before(filter.After) {
+			continue
+		}
+		if !filter.Before.IsZero() && !v.ReleaseTime.Before(filter.Before) {
+			continue
+		}
+		if match != nil && !match(c.keys[v.ID]) {
+			continue
+		}
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+// Resolve finds the version a query refers to. The query may be:
+//
+//   - an exact version ID, such as "1.20.4" or "24w14a";
+//   - "latest" or "latest-release" for the newest release, or
+//     "latest-snapshot" for the newest version of any type;
+//   - a constraint, resolved to the newest release that satisfies it.
+//
+// Constraints are space-separated terms that must all hold, optionally joined
+// by "||". A term is a wildcard such as "1.20.x" or "1.*", or a comparison of
+// =, !=, <, <=, > or >= with a version ID, e.g. ">=1.19 <1.21".
+func (c *Catalogue) Resolve(query string) (*types.Version, error) {
+	id := query
+	switch query {
+	case "", "latest", "latest-release":
+		id = c.manifest.Latest.Release
+	case "latest-snapshot":
+		id = c.manifest.Latest.Snapshot
+	}
+	if v, ok := c.Get(id); ok {
+		return v, nil
+	}
+	if !strings.ContainsAny(query, "<>=!*x|") {
+		return nil, fmt.Errorf("version '%s' not found in manifest", id)
+	}
+
+	versions, err := c.Versions(VersionFilter{Types: []string{VersionTypeRelease}, Constraint: query})
+	if err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("no release matches '%s'", query)
+	}
+	return &versions[0], nil
+}
+
+// Compare orders two version IDs, returning -1, 0 or +1. IDs missing from the
+// manifest are placed by their form alone; a weekly snapshot that is not
+// listed sorts after every release.
+func (c *Catalogue) Compare(a, b string) int {
+	return compareKeys(c.key(a), c.key(b))
+}
+
+func (c *Catalogue) key(id string) versionKey {
+	if key, ok := c.keys[id]; ok {
+		return key
+	}
+	return parseVersionID(id)
+}
+
+// parseConstraint compiles a constraint into a predicate; see Resolve.
+func (c *Catalogue) parseConstraint(s string) (func(versionKey) bool, error) {
+	var alternatives [][]func(versionKey) bool
+	for _, alternative := range strings.Split(s, "||") {
+		fields := strings.Fields(alternative)
+		var terms []func(versionKey) bool
+		for i := 0; i < len(fields); i++ {
+			term := fields[i]
+			// Allow a space between an operator and its version.
+			if strings.Trim(term, "<>=!") == "" && i+1 < len(fields) {
+				i++
+				term += fields[i]
+			}
+			match, err := c.parseTerm(term)
+			if err != nil {
+				return nil, fmt.Errorf("invalid version constraint '%s': %w", s, err)
+			}
+			terms = append(terms, match)
+		}
+		if len(terms) == 0 {
+			return nil, fmt.Errorf("invalid version constraint '%s': empty alternative", s)
+		}
+		alternatives = append(alternatives, terms)
+	}
+
+	return func(key versionKey) bool {
+		for _, terms := range alternatives {
+			all := true
+			for _, term := range terms {
+				if !term(key) {
+					all = false
+					break
+				}
+			}
+			if all {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+func (c *Catalogue) parseTerm(term string) (func(versionKey) bool, error) {
+	op := ""
+	for _, candidate := range []string{">=", "<=", "!=", "==", ">", "<", "="} {
+		if strings.HasPrefix(term, candidate) {
+			op = candidate
+			break
+		}
+	}
+	id := strings.TrimPrefix(term, op)
+
+	if id == "*" || id == "x" || strings.HasSuffix(id, ".x") || strings.HasSuffix(id, ".*") {
+		if op != "" && op != "=" && op != "==" {
+			return nil, fmt.Errorf("wildcard '%s' cannot be used with %s", id, op)
+		}
+		var prefix []int
+		if id != "*" && id != "x" {
+			var ok bool
+			if prefix, ok = parseNumbers(id[:len(id)-2]); !ok {
+				return nil, fmt.Errorf("invalid wildcard '%s'", id)
+			}
+		}
+		return func(key versionKey) bool {
+			return key.era == eraModern && key.release != nil && hasPrefix(key.release, prefix)
+		}, nil
+	}
+
+	target := c.key(id)
+	if target.release == nil && target.time.IsZero() {
+		return nil, fmt.Errorf("unknown version '%s'", id)
+	}
+	return func(key versionKey) bool {
+		cmp := compareKeys(key, target)
+		switch op {
+		case ">=":
+			return cmp >= 0
+		case "<=":
+			return cmp <= 0
+		case ">":
+			return cmp > 0
+		case "<":
+			return cmp < 0
+		case "!=":
+			return cmp != 0
+		default:
+			return cmp == 0
+		}
+	}, nil
+}
+
+// Development stages of a version, in order.
+const (
+	stageSnapshot = iota
+	stagePre
+	stageRC
+	stageRelease
+)
+
+// Eras of version IDs, in order. Everything from 1.0 on is modern.
+const (
+	eraPreClassic = iota
+	eraClassic
+	eraIndev
+	eraInfdev
+	eraAlpha
+	eraBeta
+	eraModern
+)
+
+// versionKey is the sort key of a version ID.
+type versionKey struct {
+	era     int
+	release []int // release the version leads to; nil if unknown
+	stage   int
+	order   []int     // position within the stage, e.g. the pre-release number
+	time    time.Time // release time, if listed in the manifest
+}
+
+var (
+	releasePattern        = regexp.MustCompile(`^(\d+(?:\.\d+)+)$`)
+	preReleasePattern     = regexp.MustCompile(`^(\d+(?:\.\d+)+)(?:-pre-?| Pre-Release )(\d+)$`)
+	releaseCandPattern    = regexp.MustCompile(`^(\d+(?:\.\d+)+)-rc-?(\d+)$`)
+	namedSnapshotPattern  = regexp.MustCompile(`^(\d+(?:\.\d+)+)-snapshot-?(\d+)$`)
+	weeklySnapshotPattern = regexp.MustCompile(`^(\d{2})w(\d{2})([a-z])$`)
+)
+
+// parseVersionID derives what it can of a sort key from the ID alone.
+func parseVersionID(id string) versionKey {
+	key := versionKey{era: eraModern, stage: stageSnapshot}
+	switch {
+	case strings.HasPrefix(id, "rd-"):
+		return versionKey{era: eraPreClassic}
+	case strings.HasPrefix(id, "c0."):
+		return versionKey{era: eraClassic}
+	case strings.HasPrefix(id, "inf-"):
+		return versionKey{era: eraInfdev}
+	case strings.HasPrefix(id, "in-"):
+		return versionKey{era: eraIndev}
+	case strings.HasPrefix(id, "a1."):
+		key.era, id = eraAlpha, id[1:]
+	case strings.HasPrefix(id, "b1."):
+		key.era, id = eraBeta, id[1:]
+	}
+
+	if m := releasePattern.FindStringSubmatch(id); m != nil {
+		key.release, _ = parseNumbers(m[1])
+		key.stage = stageRelease
+	} else if m := releaseCandPattern.FindStringSubmatch(id); m != nil {
+		key.release, _ = parseNumbers(m[1])
+		key.stage = stageRC
+		key.order = []int{atoi(m[2])}
+	} else if m := preReleasePattern.FindStringSubmatch(id); m != nil {
+		key.release, _ = parseNumbers(m[1])
+		key.stage = stagePre
+		key.order = []int{atoi(m[2])}
+	} else if m := namedSnapshotPattern.FindStringSubmatch(id); m != nil {
+		key.release, _ = parseNumbers(m[1])
+		key.order = []int{atoi(m[2])}
+	} else if m := weeklySnapshotPattern.FindStringSubmatch(id); m != nil {
+		key.order = []int{atoi(m[1]), atoi(m[2]), int(m[3][0])}
+	}
+	return key
+}
+
+// compareKeys orders two version keys. Keys without a release number are
+// ordered by release time when both have one, and otherwise after keys with
+// one.
+func compareKeys(a, b versionKey) int {
+	if a.era != b.era {
+		return compareInt(a.era, b.era)
+	}
+	switch {
+	case a.release != nil && b.release != nil:
+		if c := compareNumbers(a.release, b.release); c != 0 {
+			return c
+		}
+	case a.release != nil || b.release != nil:
+		if !a.time.IsZero() && !b.time.IsZero() {
+			return compareTime(a.time, b.time)
+		}
+		if a.release == nil {
+			return 1
+		}
+		return -1
+	}
+	if a.stage != b.stage {
+		return compareInt(a.stage, b.stage)
+	}
+	if a.order != nil && b.order != nil {
+		if c := compareNumbers(a.order, b.order); c != 0 {
+			return c
+		}
+	}
+	return compareTime(a.time, b.time)
+}
+
+// compareNumbers compares dotted version numbers, treating missing
+// components as zero.
+func compareNumbers(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			return compareInt(x, y)
+		}
+	}
+	return 0
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareTime(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	default:
+		return 0
+	}
+}
+
+func parseNumbers(s string) ([]int, bool) {
+	parts := strings.Split(s, ".")
+	numbers := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, false
+		}
+		numbers[i] = n
+	}
+	return numbers, true
+}
+
+func hasPrefix(numbers, prefix []int) bool {
+	if len(prefix) > len(numbers) {
+		return false
+	}
+	for i := range prefix {
+		if numbers[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,185 @@
+package download
+
+import (
+	"github.com/xDefyingGravity/gomcserver/types"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCatalogue covers the 1.7.10 and 1.16.5 hotfixes, which were released
+// while the snapshots for 1.8 and 1.17 were already out.
+func testCatalogue(t *testing.T) *Catalogue {
+	t.Helper()
+	manifest := &types.VersionManifest{}
+	manifest.Latest.Release = "1.21.1"
+	manifest.Latest.Snapshot = "24w33a"
+	for _, v := range []struct{ id, typ, date string }{
+		{"a1.0.4", VersionTypeOldAlpha, "2010-07-09"},
+		{"b1.7.3", VersionTypeOldBeta, "2011-07-08"},
+		{"1.7.2", VersionTypeRelease, "2013-10-25"},
+		{"1.7.4", VersionTypeRelease, "2013-12-10"},
+		{"14w02a", VersionTypeSnapshot, "2014-01-09"},
+		{"1.7.5", VersionTypeRelease, "2014-02-26"},
+		{"1.7.10-pre4", VersionTypeSnapshot, "2014-06-24"},
+		{"1.7.10", VersionTypeRelease, "2014-06-26"},
+		{"14w34d", VersionTypeSnapshot, "2014-08-22"},
+		{"1.8-pre1", VersionTypeSnapshot, "2014-08-28"},
+		{"1.8", VersionTypeRelease, "2014-09-02"},
+		{"1.16.4", VersionTypeRelease, "2020-11-02"},
+		{"20w45a", VersionTypeSnapshot, "2020-11-11"},
+		{"20w51a", VersionTypeSnapshot, "2020-12-16"},
+		{"1.16.5-rc1", VersionTypeSnapshot, "2021-01-13"},
+		{"1.16.5", VersionTypeRelease, "2021-01-15"},
+		{"21w03a", VersionTypeSnapshot, "2021-01-20"},
+		{"1.17-pre1", VersionTypeSnapshot, "2021-05-27"},
+		{"1.17-pre2", VersionTypeSnapshot, "2021-05-31"},
+		{"1.17-rc1", VersionTypeSnapshot, "2021-06-04"},
+		{"1.17", VersionTypeRelease, "2021-06-08"},
+		{"1.21.1", VersionTypeRelease, "2024-08-08"},
+		{"24w33a", VersionTypeSnapshot, "2024-08-15"},
+	} {
+		released, err := time.Parse(time.DateOnly, v.date)
+		if err != nil {
+			t.Fatal(err)
+		}
+		manifest.Versions = append(manifest.Versions, types.Version{ID: v.id, Type: v.typ, ReleaseTime: released})
+	}
+	return NewCatalogue(manifest)
+}
+
+func TestCatalogueCompare(t *testing.T) {
+	c := testCatalogue(t)
+	for _, tc := range []struct {
+		a, b string
+		want int
+	}{
+		// Snapshots released around a hotfix belong to the next minor release.
+		{"20w45a", "1.16.5", 1},
+		{"20w51a", "1.16.5", 1},
+		{"20w51a", "1.16.5-rc1", 1},
+		{"20w51a", "1.17-pre1", -1},
+		{"20w51a", "1.17", -1},
+		{"20w45a", "20w51a", -1},
+		{"21w03a", "20w51a", 1},
+		{"14w02a", "1.7.10", 1},
+		{"14w02a", "1.7.5", 1},
+		{"14w02a", "14w34d", -1},
+		{"14w02a", "1.8", -1},
+		{"14w34d", "1.8-pre1", -1},
+
+		// Pre-releases and release candidates.
+		{"1.17-pre1", "1.17-pre2", -1},
+		{"1.17-pre2", "1.17-rc1", -1},
+		{"1.17-rc1", "1.17", -1},
+		{"1.16.5-rc1", "1.16.5", -1},
+		{"1.16.5-rc1", "1.16.4", 1},
+		{"1.7.10-pre4", "1.7.10", -1},
+		{"1.7.10-pre4", "1.7.5", 1},
+
+		{"1.17", "1.17", 0},
+		{"1.16.5", "1.17", -1},
+		{"1.7.10", "1.8", -1},
+		{"b1.7.3", "1.7.2", -1},
+		{"a1.0.4", "b1.7.3", -1},
+		{"24w33a", "1.21.1", 1},
+
+		// IDs missing from the manifest are placed by their form.
+		{"1.30", "1.21.1", 1},
+		{"1.17.1", "1.17", 1},
+		{"99w01a", "1.21.1", 1},
+		{"1.17.1-pre1", "1.17.1", -1},
+	} {
+		if got := c.Compare(tc.a, tc.b); got != tc.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+		if got := c.Compare(tc.b, tc.a); got != -tc.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", tc.b, tc.a, got, -tc.want)
+		}
+	}
+}
+
+func TestCatalogueResolve(t *testing.T) {
+	c := testCatalogue(t)
+	for query, want := range map[string]string{
+		"":                "1.21.1",
+		"latest":          "1.21.1",
+		"latest-release":  "1.21.1",
+		"latest-snapshot": "24w33a",
+		"20w45a":          "20w45a",
+		"1.16.5":          "1.16.5",
+		"1.16.x":          "1.16.5",
+		"1.7.*":           "1.7.10",
+		"1.x":             "1.21.1",
+		">=1.7 <1.8":      "1.7.10",
+		"< 1.17":          "1.16.5",
+		"<20w45a":         "1.16.5",
+		"<1.16.5":         "1.16.4",
+		"<=20w51a":        "1.16.5",
+		"1.8.x || 1.16.x": "1.16.5",
+		"!=1.21.1":        "1.17",
+	} {
+		v, err := c.Resolve(query)
+		if err != nil {
+			t.Errorf("Resolve(%q): %v", query, err)
+			continue
+		}
+		if v.ID != want {
+			t.Errorf("Resolve(%q) = %s, want %s", query, v.ID, want)
+		}
+	}
+
+	for query, want := range map[string]string{
+		"1.99":       "not found",
+		"20w99a":     "not found",
+		"2.x":        "no release matches",
+		">1.21.1":    "no release matches",
+		"<=nope":     "unknown version",
+		">=1.* ":     "cannot be used",
+		"1.16.x || ": "empty alternative",
+	} {
+		if v, err := c.Resolve(query); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Resolve(%q) = %v, %v; want an error containing %q", query, v, err, want)
+		}
+	}
+}
+
+func TestCatalogueConstraints(t *testing.T) {
+	c := testCatalogue(t)
+	for constraint, want := range map[string][]string{
+		"1.16.x":             {"1.16.5", "1.16.5-rc1", "1.16.4"},
+		"1.17.x":             {"1.17", "1.17-rc1", "1.17-pre2", "1.17-pre1", "21w03a", "20w51a", "20w45a"},
+		"1.7.x":              {"1.7.10", "1.7.10-pre4", "1.7.5", "1.7.4", "1.7.2"},
+		"1.8.x":              {"1.8", "1.8-pre1", "14w34d", "14w02a"},
+		">1.16.5":            {"24w33a", "1.21.1", "1.17", "1.17-rc1", "1.17-pre2", "1.17-pre1", "21w03a", "20w51a", "20w45a"},
+		">=1.17":             {"24w33a", "1.21.1", "1.17"},
+		">1.16.5 <1.17-pre1": {"21w03a", "20w51a", "20w45a"},
+		"<1.7.4":             {"1.7.2", "b1.7.3", "a1.0.4"},
+	} {
+		versions, err := c.Versions(VersionFilter{Constraint: constraint})
+		if err != nil {
+			t.Errorf("Versions(%q): %v", constraint, err)
+			continue
+		}
+		var ids []string
+		for _, v := range versions {
+			ids = append(ids, v.ID)
+		}
+		if !reflect.DeepEqual(ids, want) {
+			t.Errorf("Versions(%q) = %q, want %q", constraint, ids, want)
+		}
+	}
+
+	versions, err := c.Versions(VersionFilter{Types: []string{VersionTypeSnapshot}, Constraint: ">=1.16.5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, v := range versions {
+		ids = append(ids, v.ID)
+	}
+	if want := []string{"24w33a", "1.17-rc1", "1.17-pre2", "1.17-pre1", "21w03a", "20w51a", "20w45a"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("snapshots >=1.16.5 = %q, want %q", ids, want)
+	}
+}
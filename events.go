@@ -0,0 +1,232 @@
+package gomcserver
+
+import (
+	"github.com/xDefyingGravity/gomcserver/download"
+	"sync"
+	"time"
+)
+
+// Event is implemented by every event a Server publishes.
+type Event interface {
+	isEvent()
+}
+
+// StdoutLine is published for every line the server writes to stdout.
+type StdoutLine struct {
+	Line string
+}
+
+// StderrLine is published for every line the server writes to stderr.
+type StderrLine struct {
+	Line string
+}
+
+// LogLine is published for every stdout line, parsed into a LogEntry.
+type LogLine struct {
+	LogEntry
+}
+
+// PlayerJoined is published when a player joins the game.
+type PlayerJoined struct {
+	Player string
+	Count  int // players online after the join
+}
+
+// PlayerLeft is published when a player leaves the game.
+type PlayerLeft struct {
+	Player string
+	Count  int // players online after the leave
+}
+
+// ChatMessage is published when a player sends a chat message.
+type ChatMessage struct {
+	Player  string
+	Message string
+}
+
+// SayMessage is published for /say broadcasts from a player, the console or RCON.
+type SayMessage struct {
+	Sender  string
+	Message string
+}
+
+// EmoteMessage is published for /me actions.
+type EmoteMessage struct {
+	Player string
+	Action string
+}
+
+// PlayerDied is published when a vanilla death message is printed.
+type PlayerDied struct {
+	Player  string
+	Message string // full death message, e.g. "Steve was slain by Zombie"
+	Killer  string // entity or player responsible, if named
+	Weapon  string // item used by the killer, if named
+}
+
+// AdvancementMade is published when a player earns an advancement, challenge or goal.
+type AdvancementMade struct {
+	Player      string
+	Advancement string
+	Kind        AdvancementKind
+}
+
+// CommandIssued is published when a player runs a command. Only Bukkit-based
+// servers log these lines.
+type CommandIssued struct {
+	Player  string
+	Command string
+}
+
+// ServerReady is published once the server has finished loading.
+type ServerReady struct {
+	LoadTime time.Duration // startup time reported by the server, if it could be parsed
+}
+
+// ServerExited is published after the server process has been reaped.
+type ServerExited struct {
+	ExitInfo
+}
+
+// StateChanged is published whenever the lifecycle state changes.
+type StateChanged struct {
+	From ServerState
+	To   ServerState
+}
+
+// Restarting is published when the restart policy schedules a restart.
+type Restarting struct {
+	Attempt int           // restart attempt within the policy window, starting at 1
+	Delay   time.Duration // time until the process is relaunched
+}
+
+// RestartGaveUp is published when the restart policy stops retrying.
+type RestartGaveUp struct {
+	ExitInfo
+}
+
+// BackupCompleted is published when a backup finishes, successfully or not.
+type BackupCompleted struct {
+	Path string
+	Err  error
+}
+
+// DownloadProgress is published periodically while Start downloads the
+// server or a Java runtime, and once more with Done set when each file
+// completes.
+type DownloadProgress struct {
+	download.Progress
+}
+
+func (StdoutLine) isEvent()       {}
+func (StderrLine) isEvent()       {}
+func (LogLine) isEvent()          {}
+func (PlayerJoined) isEvent()     {}
+func (PlayerLeft) isEvent()       {}
+func (ChatMessage) isEvent()      {}
+func (SayMessage) isEvent()       {}
+func (EmoteMessage) isEvent()     {}
+func (PlayerDied) isEvent()       {}
+func (AdvancementMade) isEvent()  {}
+func (CommandIssued) isEvent()    {}
+func (ServerReady) isEvent()      {}
+func (ServerExited) isEvent()     {}
+func (StateChanged) isEvent()     {}
+func (Restarting) isEvent()       {}
+func (RestartGaveUp) isEvent()    {}
+func (BackupCompleted) isEvent()  {}
+func (DownloadProgress) isEvent() {}
+
+// eventBus dispatches events to any number of subscribers.
+type eventBus struct {
+	mu       sync.RWMutex
+	nextID   uint64
+	handlers map[uint64]func(Event)
+}
+
+func (b *eventBus) subscribe(fn func(Event)) func() {
+	b.mu.Lock()
+	if b.handlers == nil {
+		b.handlers = make(map[uint64]func(Event))
+	}
+	id := b.nextID
+	b.nextID++
+	b.handlers[id] = fn
+	b.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.handlers, id)
+			b.mu.Unlock()
+		})
+	}
+}
+
+func (b *eventBus) publish(ev Event) {
+	b.mu.RLock()
+	handlers := make([]func(Event), 0, len(b.handlers))
+	for _, fn := range b.handlers {
+		handlers = append(handlers, fn)
+	}
+	b.mu.RUnlock()
+
+	for _, fn := range handlers {
+		fn(ev)
+	}
+}
+
+// Subscribe registers fn for events of type E and returns a function that
+// unsubscribes it. Handlers run synchronously on the goroutine that publishes the
+// event (usually the stdout reader), so they should return quickly; use
+// SubscribeChan for slow consumers.
+func Subscribe[E Event](s *Server, fn func(E)) (unsubscribe func()) {
+	return s.events.subscribe(func(ev Event) {
+		if e, ok := ev.(E); ok {
+			fn(e)
+		}
+	})
+}
+
+// SubscribeAll registers fn for every event and returns a function that
+// unsubscribes it.
+func (s *Server) SubscribeAll(fn func(Event)) (unsubscribe func()) {
+	return s.events.subscribe(fn)
+}
+
+// SubscribeChan delivers events of type E on a channel with the given buffer size.
+// When the buffer is full, new events are dropped rather than blocking the
+// publisher. Unsubscribing closes the channel.
+func SubscribeChan[E Event](s *Server, buffer int) (events <-chan E, unsubscribe func()) {
+	ch := make(chan E, buffer)
+	var mu sync.Mutex
+	closed := false
+
+	unsub := Subscribe(s, func(e E) {
+		mu.Lock()
+		defer mu.Unlock()
+		if closed {
+			return
+		}
+		select {
+		case ch <- e:
+		default:
+		}
+	})
+
+	return ch, func() {
+		unsub()
+		mu.Lock()
+		defer mu.Unlock()
+		if !closed {
+			closed = true
+			close(ch)
+		}
+	}
+}
+
+// emit publishes an event to the server's subscribers.
+func (s *Server) emit(ev Event) {
+	s.events.publish(ev)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/xDefyingGravity/gomcserver"
 	"io"
@@ -13,25 +14,17 @@ func main() {
 
 	_, pw := io.Pipe()
 
-	if err := myServer.SetEventListener("stdout", func(output string) {
-		fmt.Print("[stdout] " + output)
-	}); err != nil {
-		fmt.Println("[error] failed to set stdout listener:", err)
-		return
-	}
+	gomcserver.Subscribe(myServer, func(e gomcserver.StdoutLine) {
+		fmt.Println("[stdout] " + e.Line)
+	})
 
-	if err := myServer.SetEventListener("playerJoin", func(playerName string, newCount int) {
-		fmt.Println("[player join] " + playerName)
-	}); err != nil {
-		fmt.Println("[error] failed to set playerJoin listener:", err)
-		return
-	}
+	gomcserver.Subscribe(myServer, func(e gomcserver.PlayerJoined) {
+		fmt.Println("[player join] " + e.Player)
+	})
 
-	if err := myServer.SetEventListener("playerLeave", func(playerName string, newCount int) {
-		fmt.Println("[player leave] " + playerName)
-	}); err != nil {
-		fmt.Println("[error] failed to set playerLeave listener:", err)
-	}
+	gomcserver.Subscribe(myServer, func(e gomcserver.PlayerLeft) {
+		fmt.Println("[player leave] " + e.Player)
+	})
 
 	myServer.SetProperty("gamemode", "creative")
 
@@ -43,6 +36,10 @@ func main() {
 		return
 	}
 
+	go func() {
+		_ = gomcserver.HandleSignals(context.Background(), myServer)
+	}()
+
 	ticks := 0
 	for {
 		if !myServer.IsRunning() {
@@ -0,0 +1,213 @@
+package gomcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// execQuietPeriod is how long ExecCommand waits for further output after the
+	// last line before considering the response complete.
+	execQuietPeriod = 300 * time.Millisecond
+	// execNoOutputTimeout bounds the wait for commands that print nothing.
+	execNoOutputTimeout = 2 * time.Second
+)
+
+// CommandResult holds the console output produced in response to a command.
+type CommandResult struct {
+	Command string
+	Entries []LogEntry
+}
+
+// Lines returns the messages of the captured log entries.
+func (r CommandResult) Lines() []string {
+	lines := make([]string, len(r.Entries))
+	for i, entry := range r.Entries {
+		lines[i] = entry.Message
+	}
+	return lines
+}
+
+// Output returns the captured messages joined by newlines.
+func (r CommandResult) Output() string {
+	return strings.Join(r.Lines(), "\n")
+}
+
+// PlayerList is the parsed response of the "list" command.
+type PlayerList struct {
+	Online  int
+	Max     int
+	Players []string
+}
+
+var (
+	listPattern        = regexp.MustCompile(`^There are (\d+) of a max of (\d+) players online:\s*(.*)$`)
+	whitelistPattern   = regexp.MustCompile(`^There are (\d+) whitelisted player(?:s|\(s\))?:\s*(.*)$`)
+	noWhitelistPattern = regexp.MustCompile(`^There are no whitelisted players`)
+	scorePattern       = regexp.MustCompile(`^(\S+) has (-?\d+) \[(.+)\]$`)
+	noScorePattern     = regexp.MustCompile(`^(?:Can't get value of|No score for) `)
+	dataPattern        = regexp.MustCompile(`has the following (?:entity|block|storage) data: (.*)$`)
+	dataErrorPattern   = regexp.MustCompile(`^(?:No entity was found|Found no elements matching|The target block is not a block entity)`)
+)
+
+// ExecCommand sends a command and captures its response. Over RCON the response
+// is returned directly. Over stdin, the log lines printed after the command are
+// captured instead: calls are serialised so that output from concurrent commands
+// is not interleaved, and the response is considered complete once the server
+// has been quiet for a short period, or after a timeout if it prints nothing.
+func (s *Server) ExecCommand(ctx context.Context, command string) (CommandResult, error) {
+	return s.execCommand(ctx, command, nil)
+}
+
+// execCommand is ExecCommand with an optional completion check that ends the
+// capture as soon as the expected response has been seen.
+func (s *Server) execCommand(ctx context.Context, command string, complete func(LogEntry) bool) (CommandResult, error) {
+	s.mu.Lock()
+	running, transport := s.running, s.transport
+	s.mu.Unlock()
+	if !running {
+		return CommandResult{Command: command}, errors.New("server is not running")
+	}
+	if transport == TransportRcon {
+		response, err := s.execRcon(ctx, command)
+		if err != nil {
+			return CommandResult{Command: command}, err
+		}
+		return rconResult(command, response), nil
+	}
+
+	s.execMu.Lock()
+	defer s.execMu.Unlock()
+
+	lines, unsubscribe := SubscribeChan[LogLine](s, 256)
+	defer unsubscribe()
+
+	result := CommandResult{Command: command}
+	if err := s.SendCommand(command); err != nil {
+		return result, err
+	}
+
+	timer := time.NewTimer(execNoOutputTimeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		case <-s.Done():
+			return result, errors.New("server exited while waiting for command output")
+		case <-timer.C:
+			return result, nil
+		case line := <-lines:
+			if chatPattern.MatchString(line.Message) {
+				continue
+			}
+			result.Entries = append(result.Entries, line.LogEntry)
+			if complete != nil && complete(line.LogEntry) {
+				return result, nil
+			}
+			if !timer.Stop() {
+				<-timer.C
+			}
+			timer.Reset(execQuietPeriod)
+		}
+	}
+}
+
+// ListPlayers runs "list" and returns the online player count, capacity and names.
+func (s *Server) ListPlayers(ctx context.Context) (PlayerList, error) {
+	result, err := s.execCommand(ctx, "list", matches(listPattern))
+	if err != nil {
+		return PlayerList{}, err
+	}
+	for _, line := range result.Lines() {
+		if m := listPattern.FindStringSubmatch(line); m != nil {
+			online, _ := strconv.Atoi(m[1])
+			maxPlayers, _ := strconv.Atoi(m[2])
+			return PlayerList{Online: online, Max: maxPlayers, Players: splitNames(m[3])}, nil
+		}
+	}
+	return PlayerList{}, fmt.Errorf("unexpected response to list: %q", result.Output())
+}
+
+// WhitelistList runs "whitelist list" and returns the whitelisted player names.
+func (s *Server) WhitelistList(ctx context.Context) ([]string, error) {
+	result, err := s.execCommand(ctx, "whitelist list", matches(whitelistPattern, noWhitelistPattern))
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range result.Lines() {
+		if noWhitelistPattern.MatchString(line) {
+			return []string{}, nil
+		}
+		if m := whitelistPattern.FindStringSubmatch(line); m != nil {
+			return splitNames(m[2]), nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected response to whitelist list: %q", result.Output())
+}
+
+// GetScore runs "scoreboard players get" for the given target and objective.
+func (s *Server) GetScore(ctx context.Context, target, objective string) (int, error) {
+	command := "scoreboard players get " + target + " " + objective
+	result, err := s.execCommand(ctx, command, matches(scorePattern, noScorePattern))
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range result.Lines() {
+		if m := scorePattern.FindStringSubmatch(line); m != nil {
+			return strconv.Atoi(m[2])
+		}
+		if noScorePattern.MatchString(line) {
+			return 0, errors.New(line)
+		}
+	}
+	return 0, fmt.Errorf("unexpected response to %s: %q", command, result.Output())
+}
+
+// GetData runs "data get" with the given arguments (e.g. "entity Steve Pos") and
+// returns the SNBT value printed by the server.
+func (s *Server) GetData(ctx context.Context, args string) (string, error) {
+	command := "data get " + args
+	result, err := s.execCommand(ctx, command, matches(dataPattern, dataErrorPattern))
+	if err != nil {
+		return "", err
+	}
+	for _, line := range result.Lines() {
+		if m := dataPattern.FindStringSubmatch(line); m != nil {
+			return m[1], nil
+		}
+		if dataErrorPattern.MatchString(line) {
+			return "", errors.New(line)
+		}
+	}
+	return "", fmt.Errorf("unexpected response to %s: %q", command, result.Output())
+}
+
+// matches returns a completion check that accepts entries matching any pattern.
+func matches(patterns ...*regexp.Regexp) func(LogEntry) bool {
+	return func(entry LogEntry) bool {
+		for _, p := range patterns {
+			if p.MatchString(entry.Message) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// splitNames splits a comma-separated player list, ignoring empty entries.
+func splitNames(list string) []string {
+	names := []string{}
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,142 @@
+package gomcserver
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ExitInfo describes how a server process terminated.
+type ExitInfo struct {
+	Code      int            // process exit code, or -1 if it was terminated by a signal
+	Signal    syscall.Signal // signal that terminated the process, or 0
+	Time      time.Time      // when the process was reaped
+	Uptime    time.Duration  // how long the process was running
+	Requested bool           // true if the exit was initiated through Stop
+	Err       error          // error returned by Wait that is not a plain non-zero exit
+}
+
+// Crashed reports whether the process exited abnormally without being asked to stop.
+func (e ExitInfo) Crashed() bool {
+	if e.Requested {
+		return false
+	}
+	return e.Code != 0 || e.Signal != 0 || e.Err != nil
+}
+
+// Done returns a channel that is closed when the current (or most recent) server
+// process exits, or when a start fails before a process is launched. If the
+// server has never been started, the returned channel is closed.
+func (s *Server) Done() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done == nil {
+		closed := make(chan struct{})
+		close(closed)
+		return closed
+	}
+	return s.done
+}
+
+// Wait blocks until the server process exits and returns how it terminated.
+func (s *Server) Wait() (ExitInfo, error) {
+	return s.WaitContext(context.Background())
+}
+
+// WaitContext is like Wait but returns ctx.Err() if ctx is done before the process exits.
+func (s *Server) WaitContext(ctx context.Context) (ExitInfo, error) {
+	s.mu.Lock()
+	done := s.done
+	s.mu.Unlock()
+	if done == nil {
+		return ExitInfo{}, errors.New("server has not been started")
+	}
+	select {
+	case <-done:
+		info, _ := s.LastExit()
+		return info, nil
+	case <-ctx.Done():
+		return ExitInfo{}, ctx.Err()
+	}
+}
+
+// LastExit returns information about the most recent process exit, if any.
+func (s *Server) LastExit() (ExitInfo, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lastExit == nil {
+		return ExitInfo{}, false
+	}
+	return *s.lastExit, true
+}
+
+// supervise waits for the output readers to drain, reaps the process and records
+// its exit status before notifying listeners.
+func (s *Server) supervise(proc Process, readers *sync.WaitGroup, done chan struct{}) {
+	readers.Wait()
+	err := proc.Wait()
+
+	s.mu.Lock()
+	startedAt, requested := s.startedAt, s.stopRequested
+	s.mu.Unlock()
+	s.handleExit(newExitInfo(err, startedAt, requested), done)
+}
+
+// handleExit records an exit, releases per-process resources and notifies
+// listeners before applying the restart policy.
+func (s *Server) handleExit(info ExitInfo, done chan struct{}) {
+	s.stopTailing()
+	s.closeConsoleFifo()
+	s.closeRcon()
+	s.removeRuntimeState()
+	s.releaseLock()
+
+	s.mu.Lock()
+	s.lastExit = &info
+	s.running = false
+	s.pid = -1
+	s.process = nil
+	s.stdinPipe = nil
+	s.players = nil
+	s.mu.Unlock()
+
+	if info.Crashed() {
+		s.setState(StateCrashed)
+	} else {
+		s.setState(StateStopped)
+	}
+	close(done)
+
+	s.emit(ServerExited{ExitInfo: info})
+	s.maybeRestart(info)
+}
+
+func newExitInfo(err error, startedAt time.Time, requested bool) ExitInfo {
+	now := time.Now()
+	info := ExitInfo{
+		Time:      now,
+		Uptime:    now.Sub(startedAt),
+		Requested: requested,
+	}
+
+	var exitErr *exec.ExitError
+	var coder interface{ ExitCode() int }
+	switch {
+	case err == nil:
+		info.Code = 0
+	case errors.As(err, &exitErr):
+		info.Code = exitErr.ExitCode()
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			info.Signal = status.Signal()
+		}
+	case errors.As(err, &coder):
+		info.Code = coder.ExitCode()
+	default:
+		info.Code = -1
+		info.Err = err
+	}
+	return info
+}
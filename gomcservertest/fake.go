@@ -0,0 +1,443 @@
+// Package gomcservertest provides a scriptable fake Minecraft server for
+// testing code built on gomcserver without Java or a server jar.
+//
+// A Fake implements gomcserver.Launcher. Each launch starts a fake process that
+// prints a vanilla startup log ending in the "Done" line, answers common console
+// commands and can be driven from the test to simulate players and crashes:
+//
+//	srv, fake := gomcservertest.NewServer(t.TempDir())
+//	if err := srv.Start(fake.StartOptions()); err != nil {
+//		t.Fatal(err)
+//	}
+//	_ = srv.WaitUntilReady(ctx)
+//	fake.Join("Steve")
+//	fake.Chat("Steve", "hello")
+package gomcservertest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/xDefyingGravity/gomcserver"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Handler answers a console command. It receives the arguments following the
+// command name and returns the lines to log in response.
+type Handler func(args string) []string
+
+// Fake is a scriptable stand-in for the server JVM. Its methods act on the
+// most recently launched process and are safe for concurrent use.
+type Fake struct {
+	Version      string        // version printed in the startup log, "1.21.4" by default
+	MaxPlayers   int           // reported by the list command, 20 by default
+	StartupDelay time.Duration // delay before the "Done" line
+	LaunchErr    error         // if set, Launch fails with this error
+
+	mu       sync.Mutex
+	proc     *process
+	handlers map[string]Handler
+	commands []string
+	launches int
+}
+
+// New returns a Fake with no custom command handlers.
+func New() *Fake {
+	return &Fake{handlers: make(map[string]Handler)}
+}
+
+// NewServer returns a server in dir that accepts the EULA and fits in any
+// machine's memory, together with the Fake to start it with.
+func NewServer(dir string) (*gomcserver.Server, *Fake) {
+	srv := gomcserver.NewServer(dir, "fake")
+	srv.AcceptEULA()
+	srv.MinMemoryMB = 512
+	srv.MaxMemoryMB = 512
+	return srv, New()
+}
+
+// StartOptions returns options that launch the server through f without
+// downloading a jar or looking for Java. Output is discarded.
+func (f *Fake) StartOptions() *gomcserver.StartOptions {
+	javaPath := "java"
+	skipDownload := true
+	return &gomcserver.StartOptions{
+		JavaPath:     &javaPath,
+		StdoutPipe:   io.Discard,
+		StderrPipe:   io.Discard,
+		Launcher:     f,
+		SkipDownload: &skipDownload,
+	}
+}
+
+// Handle registers fn to answer command, replacing any built-in response.
+func (f *Fake) Handle(command string, fn Handler) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.handlers == nil {
+		f.handlers = make(map[string]Handler)
+	}
+	f.handlers[command] = fn
+}
+
+// Commands returns every console command received so far, across launches.
+func (f *Fake) Commands() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string{}, f.commands...)
+}
+
+// Launches returns how many times the fake process was started.
+func (f *Fake) Launches() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.launches
+}
+
+// Running reports whether the current fake process is alive.
+func (f *Fake) Running() bool {
+	p := f.current()
+	return p != nil && !p.exited()
+}
+
+// Launch implements gomcserver.Launcher. Detached processes are not supported.
+func (f *Fake) Launch(spec gomcserver.ProcessSpec) (gomcserver.Process, error) {
+	if spec.Detached {
+		return nil, errors.New("gomcservertest: detached mode is not supported")
+	}
+	if f.LaunchErr != nil {
+		return nil, f.LaunchErr
+	}
+
+	p := newProcess(f)
+	f.mu.Lock()
+	f.proc = p
+	f.launches++
+	f.mu.Unlock()
+
+	go p.run()
+	return p, nil
+}
+
+// Log prints message from the server thread at the given level, e.g. "INFO".
+func (f *Fake) Log(level, message string) {
+	if p := f.current(); p != nil {
+		p.log("Server thread", level, message)
+	}
+}
+
+// Join simulates a player connecting.
+func (f *Fake) Join(player string) {
+	p := f.current()
+	if p == nil {
+		return
+	}
+	p.log("User Authenticator #1", "INFO", fmt.Sprintf("UUID of player %s is %s", player, offlineUUID(player)))
+	p.log("Server thread", "INFO", fmt.Sprintf("%s[/127.0.0.1:%d] logged in with entity id %d at (0.5, 64.0, 0.5)", player, 50000+len(player), 100+len(player)))
+	p.log("Server thread", "INFO", player+" joined the game")
+	p.setOnline(player, true)
+}
+
+// Leave simulates a player disconnecting.
+func (f *Fake) Leave(player string) {
+	p := f.current()
+	if p == nil {
+		return
+	}
+	p.log("Server thread", "INFO", player+" lost connection: Disconnected")
+	p.log("Server thread", "INFO", player+" left the game")
+	p.setOnline(player, false)
+}
+
+// Chat simulates a chat message from player.
+func (f *Fake) Chat(player, message string) {
+	if p := f.current(); p != nil {
+		p.log("Server thread", "INFO", fmt.Sprintf("<%s> %s", player, message))
+	}
+}
+
+// Crash makes the server print a crash report and exit with status 1.
+func (f *Fake) Crash(reason string) {
+	p := f.current()
+	if p == nil {
+		return
+	}
+	p.log("Server thread", "ERROR", "Encountered an unexpected exception")
+	p.stderrLine("java.lang.RuntimeException: " + reason)
+	p.stderrLine("\tat net.minecraft.server.MinecraftServer.runServer(MinecraftServer.java:700)")
+	p.log("Server thread", "ERROR", "This crash report has been saved to: ./crash-reports/crash-server.txt")
+	p.exit(1)
+}
+
+// Exit makes the server exit immediately with the given status.
+func (f *Fake) Exit(code int) {
+	if p := f.current(); p != nil {
+		p.exit(code)
+	}
+}
+
+func (f *Fake) current() *process {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.proc
+}
+
+func (f *Fake) handler(command string) Handler {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.commands = append(f.commands, command)
+	name, _, _ := strings.Cut(command, " ")
+	return f.handlers[name]
+}
+
+// ExitError reports a non-zero exit status of a fake process.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string { return fmt.Sprintf("exit status %d", e.Code) }
+
+// ExitCode returns the exit status.
+func (e *ExitError) ExitCode() int { return e.Code }
+
+// outputBuffer is the number of lines a fake process buffers like an OS pipe
+// would, so that event handlers can script the fake without deadlocking.
+const outputBuffer = 4096
+
+type outputLine struct {
+	w    *io.PipeWriter
+	text string
+}
+
+// process is one run of the fake server.
+type process struct {
+	fake *Fake
+
+	stdinR, stdoutR, stderrR *io.PipeReader
+	stdinW, stdoutW, stderrW *io.PipeWriter
+	output                   chan outputLine
+	done                     chan struct{} // closed once the output is flushed
+
+	mu     sync.Mutex // guards the fields below
+	online []string
+	code   int
+	closed bool
+}
+
+func newProcess(f *Fake) *process {
+	p := &process{
+		fake:   f,
+		output: make(chan outputLine, outputBuffer),
+		done:   make(chan struct{}),
+	}
+	p.stdinR, p.stdinW = io.Pipe()
+	p.stdoutR, p.stdoutW = io.Pipe()
+	p.stderrR, p.stderrW = io.Pipe()
+	go p.pump()
+	return p
+}
+
+func (p *process) Pid() int              { return 0 }
+func (p *process) Stdin() io.WriteCloser { return p.stdinW }
+func (p *process) Stdout() io.Reader     { return p.stdoutR }
+func (p *process) Stderr() io.Reader     { return p.stderrR }
+func (p *process) Kill() error           { p.exit(137); return nil }
+func (p *process) Signal(sig os.Signal) error {
+	if p.exited() {
+		return os.ErrProcessDone
+	}
+	switch sig {
+	case syscall.SIGKILL:
+		p.exit(137)
+	case syscall.SIGTERM, os.Interrupt:
+		// The JVM runs the server's shutdown hook before exiting.
+		p.shutdown()
+		p.exit(143)
+	}
+	return nil
+}
+
+func (p *process) Wait() error {
+	<-p.done
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.code != 0 {
+		return &ExitError{Code: p.code}
+	}
+	return nil
+}
+
+func (p *process) run() {
+	version := p.fake.Version
+	if version == "" {
+		version = "1.21.4"
+	}
+	started := time.Now()
+	p.log("ServerMain", "INFO", "Environment: Environment[sessionHost=https://sessionserver.mojang.com, servicesHost=https://api.minecraftservices.com, name=PROD]")
+	p.log("Server thread", "INFO", "Starting minecraft server version "+version)
+	p.log("Server thread", "INFO", "Loading properties")
+	p.log("Server thread", "INFO", "Default game type: SURVIVAL")
+	p.log("Server thread", "INFO", `Preparing level "world"`)
+	p.log("Server thread", "INFO", "Preparing start region for dimension minecraft:overworld")
+	select {
+	case <-time.After(p.fake.StartupDelay):
+	case <-p.done:
+		return
+	}
+	p.log("Server thread", "INFO", fmt.Sprintf(`Done (%.3fs)! For help, type "help"`, time.Since(started).Seconds()))
+
+	scanner := bufio.NewScanner(p.stdinR)
+	for scanner.Scan() {
+		p.command(strings.TrimSpace(scanner.Text()))
+		if p.exited() {
+			return
+		}
+	}
+}
+
+// command answers a console command the way a vanilla server would.
+func (p *process) command(line string) {
+	if line == "" {
+		return
+	}
+	line = strings.TrimPrefix(line, "/")
+	if handler := p.fake.handler(line); handler != nil {
+		_, args, _ := strings.Cut(line, " ")
+		for _, out := range handler(args) {
+			p.log("Server thread", "INFO", out)
+		}
+		return
+	}
+
+	name, args, _ := strings.Cut(line, " ")
+	switch name {
+	case "stop":
+		p.shutdown()
+		p.exit(0)
+	case "save-all":
+		p.log("Server thread", "INFO", "Saving the game (this may take a moment!)")
+		p.log("Server thread", "INFO", "Saved the game")
+	case "say":
+		p.log("Server thread", "INFO", "[Server] "+args)
+	case "list":
+		online := p.players()
+		max := p.fake.MaxPlayers
+		if max == 0 {
+			max = 20
+		}
+		p.log("Server thread", "INFO", fmt.Sprintf("There are %d of a max of %d players online: %s", len(online), max, strings.Join(online, ", ")))
+	case "kick":
+		player, _, _ := strings.Cut(args, " ")
+		if !p.isOnline(player) {
+			p.log("Server thread", "INFO", "No player was found")
+			return
+		}
+		p.log("Server thread", "INFO", "Kicked "+player+": Kicked by an operator")
+		p.log("Server thread", "INFO", player+" lost connection: Kicked by an operator")
+		p.log("Server thread", "INFO", player+" left the game")
+		p.setOnline(player, false)
+	default:
+		p.log("Server thread", "INFO", "Unknown or incomplete command, see below for error")
+		p.log("Server thread", "INFO", line+"<--[HERE]")
+	}
+}
+
+// shutdown prints the log lines of a clean shutdown.
+func (p *process) shutdown() {
+	p.log("Server thread", "INFO", "Stopping the server")
+	p.log("Server thread", "INFO", "Stopping server")
+	p.log("Server thread", "INFO", "Saving players")
+	p.log("Server thread", "INFO", "Saving worlds")
+	p.log("Server thread", "INFO", "ThreadedAnvilChunkStorage: All dimensions are saved")
+}
+
+func (p *process) log(thread, level, message string) {
+	p.write(p.stdoutW, fmt.Sprintf("[%s] [%s/%s]: %s", time.Now().Format("15:04:05"), thread, level, message))
+}
+
+func (p *process) stderrLine(line string) {
+	p.write(p.stderrW, line)
+}
+
+func (p *process) write(w *io.PipeWriter, line string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	p.output <- outputLine{w: w, text: line}
+}
+
+// pump copies buffered output to the pipes and closes them once the process
+// has exited.
+func (p *process) pump() {
+	for line := range p.output {
+		_, _ = io.WriteString(line.w, line.text+"\n")
+	}
+	_ = p.stdoutW.Close()
+	_ = p.stderrW.Close()
+	close(p.done)
+}
+
+// exit records the process's status and stops it. Output written before the
+// exit is still delivered. Only the first call has an effect.
+func (p *process) exit(code int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	p.closed = true
+	p.code = code
+	close(p.output)
+	_ = p.stdinR.Close()
+}
+
+func (p *process) exited() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closed
+}
+
+func (p *process) setOnline(player string, online bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, name := range p.online {
+		if name == player {
+			p.online = append(p.online[:i], p.online[i+1:]...)
+			break
+		}
+	}
+	if online {
+		p.online = append(p.online, player)
+	}
+}
+
+func (p *process) players() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string{}, p.online...)
+}
+
+func (p *process) isOnline(player string) bool {
+	for _, name := range p.players() {
+		if name == player {
+			return true
+		}
+	}
+	return false
+}
+
+// offlineUUID returns a stable UUID-formatted identifier for player.
+func offlineUUID(player string) string {
+	var sum [16]byte
+	for i, c := range []byte(player) {
+		sum[i%16] ^= c + byte(i)
+	}
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
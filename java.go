@@ -0,0 +1,72 @@
+package gomcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/xDefyingGravity/gomcserver/download"
+	"github.com/xDefyingGravity/gomcserver/java"
+)
+
+// RequiredJavaVersion returns the major Java version the installed server
+// needs, as recorded at install time or in its version data, or 0 if it is
+// unknown (for example for jars downloaded from a URL).
+func (s *Server) RequiredJavaVersion() int {
+	if inst, err := download.ReadInstallation(s.Directory); err == nil && inst.JavaVersion != 0 {
+		return inst.JavaVersion
+	}
+	data, err := download.ReadVersionData(s.Directory)
+	if err != nil {
+		return 0
+	}
+	return data.JavaVersion.MajorVersion
+}
+
+// resolveJava picks a Java runtime for the server unless StartOptions.JavaPath
+// is set, in which case it only checks that the given runtime is new enough.
+// If no installed runtime fits, one is provisioned through
+// StartOptions.JavaProvider when it is set.
+func (s *Server) resolveJava(ctx context.Context, opts *StartOptions) error {
+	required := s.RequiredJavaVersion()
+	if opts.JavaPath != nil {
+		if required == 0 {
+			return nil
+		}
+		rt, err := java.Inspect(*opts.JavaPath)
+		if err == nil && rt.Major < required {
+			return fmt.Errorf("minecraft %s requires Java %d or newer, but %s is Java %s", s.Version, required, *opts.JavaPath, rt.Version)
+		}
+		return nil
+	}
+
+	path, err := s.findJava(ctx, opts, required)
+	if err != nil {
+		return err
+	}
+	opts.JavaPath = &path
+	return nil
+}
+
+// findJava returns StartOptions.JavaPath if set, or otherwise an installed or
+// provisioned runtime for the required major version.
+func (s *Server) findJava(ctx context.Context, opts *StartOptions, required int) (string, error) {
+	if opts.JavaPath != nil {
+		return *opts.JavaPath, nil
+	}
+	rt, err := java.Find(required)
+	if errors.Is(err, java.ErrNoRuntime) && opts.JavaProvider != nil && required > 0 {
+		downloader := s.downloader(opts)
+		provider := opts.JavaProvider
+		if adoptium, ok := provider.(*java.Adoptium); ok && adoptium.Downloader == nil {
+			// Query the API through the same client as the download.
+			withDownloader := *adoptium
+			withDownloader.Downloader = downloader
+			provider = &withDownloader
+		}
+		rt, err = java.Provision(ctx, provider, *opts.CacheDir, required, downloader)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%w; install a suitable JDK or set StartOptions.JavaPath", err)
+	}
+	return rt.Path, nil
+}
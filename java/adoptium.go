@@ -0,0 +1,109 @@
+package java
+
+import (
+	"context"
+	"fmt"
+	"github.com/xDefyingGravity/gomcserver/download"
+	"net/url"
+	"runtime"
+	"strings"
+)
+
+// DefaultAdoptiumURL is the Adoptium API used when Adoptium.BaseURL is empty.
+const DefaultAdoptiumURL = "https://api.adoptium.net"
+
+// Build is a downloadable Java runtime.
+type Build struct {
+	Version  string // release name, e.g. "jdk-21.0.3+9"
+	Major    int
+	URL      string // archive location
+	Name     string // archive file name, ending in .tar.gz or .zip
+	Checksum string // hex-encoded SHA-256 of the archive
+}
+
+// Provider locates Java builds to download.
+type Provider interface {
+	// Latest returns the newest build of the given major version for the
+	// operating system and architecture, using Go's GOOS and GOARCH names.
+	Latest(ctx context.Context, major int, goos, goarch string) (*Build, error)
+}
+
+// Adoptium provides Eclipse Temurin builds from the Adoptium API.
+type Adoptium struct {
+	BaseURL   string // API root, DefaultAdoptiumURL if empty
+	ImageType string // "jdk" (default) or "jre"
+	// Downloader queries the API, download.DefaultDownloader if nil.
+	Downloader *download.Downloader
+}
+
+type adoptiumAsset struct {
+	Binary struct {
+		Package struct {
+			Checksum string `json:"checksum"`
+			Link     string `json:"link"`
+			Name     string `json:"name"`
+		} `json:"package"`
+	} `json:"binary"`
+	ReleaseName string `json:"release_name"`
+	Version     struct {
+		Major int `json:"major"`
+	} `json:"version"`
+}
+
+// Latest implements Provider.
+func (a *Adoptium) Latest(ctx context.Context, major int, goos, goarch string) (*Build, error) {
+	base := a.BaseURL
+	if base == "" {
+		base = DefaultAdoptiumURL
+	}
+	imageType := a.ImageType
+	if imageType == "" {
+		imageType = "jdk"
+	}
+	osName, arch, err := adoptiumPlatform(goos, goarch)
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+	query.Set("architecture", arch)
+	query.Set("image_type", imageType)
+	query.Set("os", osName)
+	query.Set("vendor", "eclipse")
+	endpoint := fmt.Sprintf("%s/v3/assets/latest/%d/hotspot?%s", strings.TrimRight(base, "/"), major, query.Encode())
+
+	assets, err := download.DownloadJSONWith[[]adoptiumAsset](ctx, a.Downloader, endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query Adoptium for Java %d: %w", major, err)
+	}
+	if len(*assets) == 0 {
+		return nil, fmt.Errorf("adoptium has no Java %d build for %s/%s", major, goos, goarch)
+	}
+	asset := (*assets)[0]
+	return &Build{
+		Version:  asset.ReleaseName,
+		Major:    asset.Version.Major,
+		URL:      asset.Binary.Package.Link,
+		Name:     asset.Binary.Package.Name,
+		Checksum: asset.Binary.Package.Checksum,
+	}, nil
+}
+
+func adoptiumPlatform(goos, goarch string) (string, string, error) {
+	osNames := map[string]string{"linux": "linux", "darwin": "mac", "windows": "windows"}
+	arches := map[string]string{"amd64": "x64", "arm64": "aarch64", "386": "x32", "arm": "arm", "ppc64le": "ppc64le", "s390x": "s390x"}
+	osName, ok := osNames[goos]
+	if !ok {
+		return "", "", fmt.Errorf("adoptium does not provide builds for %s", goos)
+	}
+	arch, ok := arches[goarch]
+	if !ok {
+		return "", "", fmt.Errorf("adoptium does not provide builds for %s", goarch)
+	}
+	return osName, arch, nil
+}
+
+// currentPlatform returns the platform the controller runs on.
+func currentPlatform() (string, string) {
+	return runtime.GOOS, runtime.GOARCH
+}
@@ -0,0 +1,244 @@
+// Package java discovers installed Java runtimes and picks one that can run a
+// given Minecraft version.
+package java
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoRuntime is returned by Find when no installed runtime is compatible.
+var ErrNoRuntime = errors.New("no compatible Java runtime found")
+
+// Runtime is an installed Java runtime.
+type Runtime struct {
+	Path    string // java executable
+	Home    string // installation directory, the parent of bin/
+	Version string // full version, e.g. "17.0.2" or "1.8.0_292"
+	Major   int    // feature release, e.g. 17 or 8
+	Source  string // where it was found: "JAVA_HOME", "PATH", "sdkman" or "system"
+}
+
+func (r Runtime) String() string {
+	return fmt.Sprintf("Java %s (%s)", r.Version, r.Path)
+}
+
+var versionPattern = regexp.MustCompile(`version "([^"]+)"`)
+
+// Discover returns the Java runtimes found in JAVA_HOME, on PATH, in sdkman's
+// candidates directory and in the platform's usual installation directories,
+// in that order. Runtimes whose version cannot be determined are skipped.
+func Discover() []Runtime {
+	var runtimes []Runtime
+	seen := make(map[string]bool)
+	add := func(executable, source string) {
+		resolved, err := filepath.EvalSymlinks(executable)
+		if err != nil || seen[resolved] {
+			return
+		}
+		seen[resolved] = true
+		if rt, err := Inspect(resolved); err == nil {
+			rt.Source = source
+			runtimes = append(runtimes, *rt)
+		}
+	}
+
+	if home := os.Getenv("JAVA_HOME"); home != "" {
+		add(executableIn(home), "JAVA_HOME")
+	}
+	if path, err := exec.LookPath(executableName()); err == nil {
+		add(path, "PATH")
+	}
+	for _, home := range sdkmanHomes() {
+		add(executableIn(home), "sdkman")
+	}
+	for _, home := range systemHomes() {
+		add(executableIn(home), "system")
+	}
+	return runtimes
+}
+
+// Find returns the installed runtime that best matches the required major
+// version: the oldest one that is at least as new, so that servers built for
+// an older Java do not run on a much newer one. Discovery order breaks ties.
+// A required version of 0 accepts the first runtime found.
+func Find(required int) (*Runtime, error) {
+	return pick(Discover(), required)
+}
+
+func pick(runtimes []Runtime, required int) (*Runtime, error) {
+	var best *Runtime
+	for i := range runtimes {
+		rt := &runtimes[i]
+		if rt.Major < required {
+			continue
+		}
+		if best == nil || (required > 0 && rt.Major < best.Major) {
+			best = rt
+		}
+	}
+	if best != nil {
+		return best, nil
+	}
+
+	if len(runtimes) == 0 {
+		return nil, fmt.Errorf("%w: no Java installation was found in JAVA_HOME, PATH or the usual install locations", ErrNoRuntime)
+	}
+	found := make([]string, len(runtimes))
+	for i, rt := range runtimes {
+		found[i] = rt.String()
+	}
+	return nil, fmt.Errorf("%w: Java %d or newer is required, found %s", ErrNoRuntime, required, strings.Join(found, ", "))
+}
+
+// Inspect determines the version of the java executable at path, reading the
+// release file of its installation when there is one and running
+// "java -version" otherwise.
+func Inspect(path string) (*Runtime, error) {
+	resolved, err := exec.LookPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if target, err := filepath.EvalSymlinks(resolved); err == nil {
+		resolved = target
+	}
+	path = resolved
+
+	home := filepath.Dir(filepath.Dir(path))
+	rt := &Runtime{Path: path, Home: home}
+
+	version, err := readReleaseFile(filepath.Join(home, "release"))
+	if err != nil {
+		version, err = runVersion(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	major, err := ParseMajor(version)
+	if err != nil {
+		return nil, err
+	}
+	rt.Version, rt.Major = version, major
+	return rt, nil
+}
+
+// ParseMajor extracts the feature release from a Java version string, handling
+// both the legacy "1.8.0_292" and the modern "17.0.2" schemes.
+func ParseMajor(version string) (int, error) {
+	version = strings.TrimPrefix(version, "1.")
+	end := strings.IndexFunc(version, func(r rune) bool { return r < '0' || r > '9' })
+	if end == 0 {
+		return 0, fmt.Errorf("invalid Java version %q", version)
+	}
+	if end > 0 {
+		version = version[:end]
+	}
+	return strconv.Atoi(version)
+}
+
+// readReleaseFile reads JAVA_VERSION from a JDK's release file.
+func readReleaseFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), "JAVA_VERSION="); ok {
+			return strings.Trim(value, `"`), nil
+		}
+	}
+	return "", fmt.Errorf("no JAVA_VERSION in %s", path)
+}
+
+// runVersion runs "java -version", which prints to stderr.
+func runVersion(path string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, path, "-version").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("failed to run %s -version: %w", path, err)
+	}
+	m := versionPattern.FindSubmatch(out)
+	if m == nil {
+		return "", fmt.Errorf("unrecognised output from %s -version", path)
+	}
+	return string(m[1]), nil
+}
+
+func executableName() string {
+	if runtime.GOOS == "windows" {
+		return "java.exe"
+	}
+	return "java"
+}
+
+func executableIn(home string) string {
+	return filepath.Join(home, "bin", executableName())
+}
+
+func sdkmanHomes() []string {
+	dir := os.Getenv("SDKMAN_DIR")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		dir = filepath.Join(home, ".sdkman")
+	}
+	return subdirectories(filepath.Join(dir, "candidates", "java"))
+}
+
+func systemHomes() []string {
+	switch runtime.GOOS {
+	case "windows":
+		var homes []string
+		for _, env := range []string{"ProgramFiles", "ProgramFiles(x86)"} {
+			root := os.Getenv(env)
+			if root == "" {
+				continue
+			}
+			for _, vendor := range []string{"Java", "Eclipse Adoptium", "Microsoft", "Zulu", "Amazon Corretto"} {
+				homes = append(homes, subdirectories(filepath.Join(root, vendor))...)
+			}
+		}
+		return homes
+	case "darwin":
+		var homes []string
+		for _, jvm := range subdirectories("/Library/Java/JavaVirtualMachines") {
+			homes = append(homes, filepath.Join(jvm, "Contents", "Home"))
+		}
+		return homes
+	default:
+		return subdirectories("/usr/lib/jvm")
+	}
+}
+
+// subdirectories lists the directories in dir, following symlinks.
+func subdirectories(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var dirs []string
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			dirs = append(dirs, path)
+		}
+	}
+	return dirs
+}
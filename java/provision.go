@@ -0,0 +1,254 @@
+package java
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/xDefyingGravity/gomcserver/download"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Provision returns a Java runtime of the given major version from cacheDir,
+// downloading it from provider first if it is not cached yet. Runtimes are
+// stored under cacheDir/java and shared by every server using the same cache.
+// The archive is fetched with downloader, or download.DefaultDownloader if it
+// is nil.
+func Provision(ctx context.Context, provider Provider, cacheDir string, major int, downloader *download.Downloader) (*Runtime, error) {
+	if downloader == nil {
+		downloader = download.DefaultDownloader
+	}
+	goos, goarch := currentPlatform()
+	root := filepath.Join(cacheDir, "java")
+	target := filepath.Join(root, fmt.Sprintf("%d-%s-%s", major, goos, goarch))
+
+	if rt, err := findInstalled(target); err == nil {
+		return rt, nil
+	}
+
+	build, err := provider.Latest(ctx, major, goos, goarch)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create java cache directory: %w", err)
+	}
+
+	archive, err := os.CreateTemp(root, "download-*-"+build.Name)
+	if err != nil {
+		return nil, err
+	}
+	archivePath := archive.Name()
+	_ = archive.Close()
+	defer os.Remove(archivePath)
+
+	if err := downloader.DownloadFile(ctx, build.URL, archivePath, ""); err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", build.Version, err)
+	}
+	if err := verifySHA256(archivePath, build.Checksum); err != nil {
+		return nil, fmt.Errorf("failed to verify %s: %w", build.Version, err)
+	}
+
+	// Unpack next to the final location and rename it into place, so that
+	// concurrent provisioning never exposes a partial runtime.
+	staging, err := os.MkdirTemp(root, "unpack-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(staging)
+	if err := unpack(archivePath, build.Name, staging); err != nil {
+		return nil, fmt.Errorf("failed to unpack %s: %w", build.Version, err)
+	}
+	if _, err := findInstalled(staging); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(staging, target); err != nil {
+		// Another controller may have installed the same runtime meanwhile.
+		if rt, findErr := findInstalled(target); findErr == nil {
+			return rt, nil
+		}
+		return nil, fmt.Errorf("failed to install %s: %w", build.Version, err)
+	}
+	return findInstalled(target)
+}
+
+// findInstalled locates the java executable inside an unpacked runtime. The
+// archives contain a single top-level directory, with the runtime under
+// Contents/Home on macOS.
+func findInstalled(dir string) (*Runtime, error) {
+	candidates := []string{executableIn(dir)}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			candidates = append(candidates,
+				executableIn(filepath.Join(dir, entry.Name())),
+				executableIn(filepath.Join(dir, entry.Name(), "Contents", "Home")))
+		}
+	}
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			rt, err := Inspect(candidate)
+			if err != nil {
+				return nil, err
+			}
+			rt.Source = "cache"
+			return rt, nil
+		}
+	}
+	return nil, fmt.Errorf("no java executable found in %s", dir)
+}
+
+func verifySHA256(path, expected string) error {
+	if expected == "" {
+		return errors.New("provider did not supply a checksum")
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return err
+	}
+	if actual := hex.EncodeToString(hasher.Sum(nil)); !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("sha256 mismatch: got %s, expected %s", actual, expected)
+	}
+	return nil
+}
+
+func unpack(archive, name, dest string) error {
+	switch {
+	case strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz"):
+		return untar(archive, dest)
+	case strings.HasSuffix(name, ".zip"):
+		return unzip(archive, dest)
+	default:
+		return fmt.Errorf("unsupported archive format: %s", name)
+	}
+}
+
+// safeJoin resolves an archive entry under dest, rejecting entries that would
+// escape it.
+func safeJoin(dest, name string) (string, error) {
+	path := filepath.Join(dest, filepath.FromSlash(name))
+	if path != dest && !strings.HasPrefix(path, dest+string(os.PathSeparator)) {
+		return "", fmt.Errorf("archive entry %q escapes the destination", name)
+	}
+	return path, nil
+}
+
+func untar(archive, dest string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		path, err := safeJoin(dest, header.Name)
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeFile(path, tr, fs.FileMode(header.Mode).Perm()); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if filepath.IsAbs(header.Linkname) {
+				return fmt.Errorf("archive entry %q links outside the archive", header.Name)
+			}
+			if _, err := safeJoin(dest, filepath.Join(filepath.Dir(header.Name), header.Linkname)); err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+			if err := os.Symlink(header.Linkname, path); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func unzip(archive, dest string) error {
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	for _, file := range r.File {
+		path, err := safeJoin(dest, file.Name)
+		if err != nil {
+			return err
+		}
+		if file.FileInfo().IsDir() {
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return err
+		}
+		mode := file.Mode().Perm()
+		if mode == 0 {
+			mode = 0644
+		}
+		err = writeFile(path, rc, mode)
+		_ = rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeFile(path string, r io.Reader, mode fs.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
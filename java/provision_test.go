@@ -0,0 +1,162 @@
+package java
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/xDefyingGravity/gomcserver/download"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// runtimeArchive builds a tar.gz holding a minimal JDK layout whose release
+// file identifies it as version.
+func runtimeArchive(t *testing.T, version string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	files := []struct {
+		name, body string
+		mode       int64
+	}{
+		{"jdk/release", "JAVA_VERSION=\"" + version + "\"\n", 0644},
+		{"jdk/bin/" + executableName(), "#!/bin/sh\n", 0755},
+	}
+	for _, f := range files {
+		hdr := &tar.Header{Name: f.name, Mode: f.mode, Size: int64(len(f.body)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(f.body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// fakeAdoptium serves the assets API and a single runtime archive, advertising
+// the checksum of advertised. It counts archive downloads.
+func fakeAdoptium(t *testing.T, major int, advertised, archive []byte) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	sum := sha256.Sum256(advertised)
+	var downloads atomic.Int32
+	var srv *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v3/assets/latest/", func(w http.ResponseWriter, r *http.Request) {
+		var asset adoptiumAsset
+		asset.ReleaseName = "jdk-21.0.3+9"
+		asset.Version.Major = major
+		asset.Binary.Package.Name = "jdk.tar.gz"
+		asset.Binary.Package.Link = srv.URL + "/archive/jdk.tar.gz"
+		asset.Binary.Package.Checksum = hex.EncodeToString(sum[:])
+		_ = json.NewEncoder(w).Encode([]adoptiumAsset{asset})
+	})
+	mux.HandleFunc("/archive/jdk.tar.gz", func(w http.ResponseWriter, r *http.Request) {
+		downloads.Add(1)
+		_, _ = w.Write(archive)
+	})
+	srv = httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv, &downloads
+}
+
+func TestProvision(t *testing.T) {
+	archive := runtimeArchive(t, "21.0.3")
+	srv, downloads := fakeAdoptium(t, 21, archive, archive)
+	cacheDir := t.TempDir()
+
+	var progressed atomic.Bool
+	downloader := &download.Downloader{
+		Client:   srv.Client(),
+		Progress: func(download.Progress) { progressed.Store(true) },
+	}
+	rt, err := Provision(context.Background(), &Adoptium{BaseURL: srv.URL}, cacheDir, 21, downloader)
+	if err != nil {
+		t.Fatalf("Provision: %v", err)
+	}
+	if rt.Major != 21 || rt.Version != "21.0.3" || rt.Source != "cache" {
+		t.Errorf("runtime = %+v, want cached Java 21.0.3", rt)
+	}
+	if rel, err := filepath.Rel(cacheDir, rt.Path); err != nil || !filepath.IsLocal(rel) {
+		t.Errorf("runtime path %s is outside the cache %s", rt.Path, cacheDir)
+	}
+	if !progressed.Load() {
+		t.Error("download did not report progress through the given Downloader")
+	}
+
+	// A second call is served from the cache.
+	if _, err := Provision(context.Background(), &Adoptium{BaseURL: srv.URL}, cacheDir, 21, downloader); err != nil {
+		t.Fatalf("second Provision: %v", err)
+	}
+	if n := downloads.Load(); n != 1 {
+		t.Errorf("archive downloaded %d times, want 1", n)
+	}
+}
+
+func TestProvisionRejectsChecksumMismatch(t *testing.T) {
+	srv, _ := fakeAdoptium(t, 21, runtimeArchive(t, "21.0.3"), runtimeArchive(t, "17.0.1"))
+	cacheDir := t.TempDir()
+	_, err := Provision(context.Background(), &Adoptium{BaseURL: srv.URL}, cacheDir, 21, &download.Downloader{Client: srv.Client()})
+	if err == nil {
+		t.Fatal("Provision succeeded with a tampered archive")
+	}
+
+	goos, goarch := currentPlatform()
+	target := filepath.Join(cacheDir, "java", fmt.Sprintf("21-%s-%s", goos, goarch))
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Errorf("tampered runtime was installed at %s", target)
+	}
+	// Neither the archive nor the staging directory is left behind.
+	entries, err := os.ReadDir(filepath.Join(cacheDir, "java"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		t.Errorf("leftover %s in the java cache", entry.Name())
+	}
+}
+
+// countingTransport counts the requests it forwards.
+type countingTransport struct {
+	next     http.RoundTripper
+	requests atomic.Int32
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.requests.Add(1)
+	return c.next.RoundTrip(req)
+}
+
+func TestAdoptiumUsesDownloader(t *testing.T) {
+	archive := runtimeArchive(t, "21.0.3")
+	srv, _ := fakeAdoptium(t, 21, archive, archive)
+	transport := &countingTransport{next: srv.Client().Transport}
+	downloader := &download.Downloader{Client: &http.Client{Transport: transport}}
+
+	build, err := (&Adoptium{BaseURL: srv.URL, Downloader: downloader}).Latest(context.Background(), 21, "linux", "amd64")
+	if err != nil {
+		t.Fatalf("Latest: %v", err)
+	}
+	if build.Major != 21 || build.Name != "jdk.tar.gz" {
+		t.Errorf("Latest = %+v, want Java 21 jdk.tar.gz", build)
+	}
+	if n := transport.requests.Load(); n != 1 {
+		t.Errorf("API requests through the Downloader = %d, want 1", n)
+	}
+}
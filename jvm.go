@@ -0,0 +1,335 @@
+package gomcserver
+
+import (
+	"fmt"
+	"github.com/xDefyingGravity/gomcserver/java"
+	"strconv"
+	"strings"
+)
+
+// JvmPreset selects a base set of JVM flags for a JvmOptionsBuilder.
+type JvmPreset int
+
+const (
+	// JvmPresetNone adds no flags besides the heap size.
+	JvmPresetNone JvmPreset = iota
+	// JvmPresetDefault is G1 with experimental options unlocked, the flags used
+	// when StartOptions sets neither JvmOptions nor Jvm.
+	JvmPresetDefault
+	// JvmPresetAikar is Aikar's tuned G1 configuration, adjusted for heaps
+	// above 12 GB.
+	JvmPresetAikar
+	// JvmPresetZGC uses the Z garbage collector (Java 15 or newer).
+	JvmPresetZGC
+	// JvmPresetGenerationalZGC uses generational ZGC (Java 21 or newer). On
+	// Java 23 and newer, where ZGC is generational by default, the obsolete
+	// -XX:+ZGenerational flag is left out.
+	JvmPresetGenerationalZGC
+	// JvmPresetLowMemory trades throughput for a small footprint on hosts
+	// with little memory.
+	JvmPresetLowMemory
+)
+
+// largeHeapMB is the heap size above which Aikar's flags use larger G1 regions.
+const largeHeapMB = 12 * 1024
+
+// generationalZGCDefaultJava is the first Java release whose ZGC is generational
+// by default and deprecates -XX:+ZGenerational.
+const generationalZGCDefaultJava = 23
+
+// flags returns the preset's flags for the heap size and Java major version,
+// 0 if unknown.
+func (p JvmPreset) flags(maxMemoryMB, javaVersion int) []string {
+	switch p {
+	case JvmPresetDefault:
+		return []string{"-XX:+UseG1GC", "-XX:+UnlockExperimentalVMOptions"}
+	case JvmPresetAikar:
+		newSize, maxNewSize, regionSize, reserve, occupancy := "30", "40", "8M", "20", "15"
+		if maxMemoryMB > largeHeapMB {
+			newSize, maxNewSize, regionSize, reserve, occupancy = "40", "50", "16M", "15", "20"
+		}
+		return []string{
+			"-XX:+UseG1GC",
+			"-XX:+ParallelRefProcEnabled",
+			"-XX:MaxGCPauseMillis=200",
+			"-XX:+UnlockExperimentalVMOptions",
+			"-XX:+DisableExplicitGC",
+			"-XX:+AlwaysPreTouch",
+			"-XX:G1NewSizePercent=" + newSize,
+			"-XX:G1MaxNewSizePercent=" + maxNewSize,
+			"-XX:G1HeapRegionSize=" + regionSize,
+			"-XX:G1ReservePercent=" + reserve,
+			"-XX:G1HeapWastePercent=5",
+			"-XX:G1MixedGCCountTarget=4",
+			"-XX:InitiatingHeapOccupancyPercent=" + occupancy,
+			"-XX:G1MixedGCLiveThresholdPercent=90",
+			"-XX:G1RSetUpdatingPauseTimePercent=5",
+			"-XX:SurvivorRatio=32",
+			"-XX:+PerfDisableSharedMem",
+			"-XX:MaxTenuringThreshold=1",
+			"-Dusing.aikars.flags=https://mcflags.emc.gs",
+			"-Daikars.new.flags=true",
+		}
+	case JvmPresetZGC:
+		return []string{"-XX:+UseZGC", "-XX:+DisableExplicitGC", "-XX:+AlwaysPreTouch", "-XX:+PerfDisableSharedMem"}
+	case JvmPresetGenerationalZGC:
+		if javaVersion >= generationalZGCDefaultJava {
+			return []string{"-XX:+UseZGC", "-XX:+DisableExplicitGC", "-XX:+AlwaysPreTouch", "-XX:+PerfDisableSharedMem"}
+		}
+		return []string{"-XX:+UseZGC", "-XX:+ZGenerational", "-XX:+DisableExplicitGC", "-XX:+AlwaysPreTouch", "-XX:+PerfDisableSharedMem"}
+	case JvmPresetLowMemory:
+		return []string{"-XX:+UseSerialGC", "-XX:+DisableExplicitGC", "-Xss512k", "-XX:ReservedCodeCacheSize=64m"}
+	default:
+		return nil
+	}
+}
+
+// minJavaVersion returns the oldest Java release supporting the preset.
+func (p JvmPreset) minJavaVersion() int {
+	switch p {
+	case JvmPresetZGC:
+		return 15
+	case JvmPresetGenerationalZGC:
+		return 21
+	default:
+		return 0
+	}
+}
+
+// JvmOptionsBuilder assembles the JVM flags for a server from a preset and
+// typed options. Set it as StartOptions.Jvm; the heap size always comes from
+// the server's MinMemoryMB and MaxMemoryMB.
+type JvmOptionsBuilder struct {
+	preset       JvmPreset
+	gcLog        string
+	heapDump     bool
+	heapDumpPath string
+	jmxPort      int
+	properties   [][2]string
+	flags        []string
+}
+
+// NewJvmOptions returns a builder starting from the given preset.
+func NewJvmOptions(preset JvmPreset) *JvmOptionsBuilder {
+	return &JvmOptionsBuilder{preset: preset}
+}
+
+// GCLog writes garbage collector logs to path, relative to the server directory.
+func (b *JvmOptionsBuilder) GCLog(path string) *JvmOptionsBuilder {
+	b.gcLog = path
+	return b
+}
+
+// HeapDumpOnOOM writes a heap dump when the server runs out of memory. An empty
+// path uses the JVM's default location, the server directory.
+func (b *JvmOptionsBuilder) HeapDumpOnOOM(path string) *JvmOptionsBuilder {
+	b.heapDump = true
+	b.heapDumpPath = path
+	return b
+}
+
+// JMX exposes the JVM's management interface on the given port without
+// authentication. The connector listens on 127.0.0.1 only and refuses
+// connections that do not come from the local machine.
+func (b *JvmOptionsBuilder) JMX(port int) *JvmOptionsBuilder {
+	b.jmxPort = port
+	return b
+}
+
+// Log4jConfigFile replaces the server's logging configuration.
+func (b *JvmOptionsBuilder) Log4jConfigFile(path string) *JvmOptionsBuilder {
+	return b.Property("log4j.configurationFile", path)
+}
+
+// DisableLog4jLookups turns off message lookups, the mitigation for
+// CVE-2021-44228 on servers that predate the patched jars.
+func (b *JvmOptionsBuilder) DisableLog4jLookups() *JvmOptionsBuilder {
+	return b.Property("log4j2.formatMsgNoLookups", "true")
+}
+
+// Property sets a Java system property (-Dkey=value).
+func (b *JvmOptionsBuilder) Property(key, value string) *JvmOptionsBuilder {
+	b.properties = append(b.properties, [2]string{key, value})
+	return b
+}
+
+// Flags appends raw JVM flags. They override preset flags with the same name.
+func (b *JvmOptionsBuilder) Flags(flags ...string) *JvmOptionsBuilder {
+	b.flags = append(b.flags, flags...)
+	return b
+}
+
+// Build returns the JVM flags for a heap of minMemoryMB to maxMemoryMB.
+// javaVersion is the major version of the runtime, or 0 if unknown. It reports
+// an error if the flags conflict with each other, with the heap size or with
+// the runtime.
+func (b *JvmOptionsBuilder) Build(minMemoryMB, maxMemoryMB, javaVersion int) ([]string, error) {
+	if required := b.preset.minJavaVersion(); javaVersion > 0 && javaVersion < required {
+		return nil, fmt.Errorf("the JVM preset requires Java %d or newer, but the runtime is Java %d", required, javaVersion)
+	}
+
+	flags := b.preset.flags(maxMemoryMB, javaVersion)
+	if b.gcLog != "" {
+		if javaVersion == 8 {
+			flags = append(flags, "-Xloggc:"+b.gcLog, "-XX:+PrintGCDetails", "-XX:+PrintGCDateStamps",
+				"-XX:+UseGCLogFileRotation", "-XX:NumberOfGCLogFiles=5", "-XX:GCLogFileSize=10M")
+		} else {
+			flags = append(flags, "-Xlog:gc*:file="+b.gcLog+":time,uptime:filecount=5,filesize=10M")
+		}
+	}
+	if b.heapDump {
+		flags = append(flags, "-XX:+HeapDumpOnOutOfMemoryError")
+		if b.heapDumpPath != "" {
+			flags = append(flags, "-XX:HeapDumpPath="+b.heapDumpPath)
+		}
+	}
+	if b.jmxPort > 0 {
+		port := strconv.Itoa(b.jmxPort)
+		flags = append(flags,
+			"-Dcom.sun.management.jmxremote.port="+port,
+			"-Dcom.sun.management.jmxremote.rmi.port="+port,
+			"-Dcom.sun.management.jmxremote.host=127.0.0.1",
+			"-Dcom.sun.management.jmxremote.local.only=true",
+			"-Dcom.sun.management.jmxremote.authenticate=false",
+			"-Dcom.sun.management.jmxremote.ssl=false",
+			"-Djava.rmi.server.hostname=127.0.0.1",
+		)
+	}
+	for _, property := range b.properties {
+		flags = append(flags, "-D"+property[0]+"="+property[1])
+	}
+	flags = append(flags, b.flags...)
+
+	return finalizeJvmArgs(flags, minMemoryMB, maxMemoryMB)
+}
+
+// jvmArgs returns the JVM flags for a launch. Without a builder, the raw
+// JvmOptions replace the default preset, as they always have.
+func (s *Server) jvmArgs(opts *StartOptions) ([]string, error) {
+	if opts.Jvm == nil {
+		if opts.JvmOptions != nil {
+			return finalizeJvmArgs(*opts.JvmOptions, s.MinMemoryMB, s.MaxMemoryMB)
+		}
+		return NewJvmOptions(JvmPresetDefault).Build(s.MinMemoryMB, s.MaxMemoryMB, 0)
+	}
+
+	javaVersion := 0
+	if rt, err := java.Inspect(*opts.JavaPath); err == nil {
+		javaVersion = rt.Major
+	}
+	builder := *opts.Jvm
+	if opts.JvmOptions != nil {
+		builder.flags = append(append([]string{}, builder.flags...), *opts.JvmOptions...)
+	}
+	return builder.Build(s.MinMemoryMB, s.MaxMemoryMB, javaVersion)
+}
+
+// finalizeJvmArgs removes duplicate flags, keeping the position of the first
+// occurrence and the value of the last, and appends the heap size. Heap flags
+// that disagree with the configured memory and competing garbage collectors
+// are reported as errors.
+func finalizeJvmArgs(flags []string, minMemoryMB, maxMemoryMB int) ([]string, error) {
+	out := make([]string, 0, len(flags)+2)
+	index := make(map[string]int)
+	collector := ""
+
+	for _, flag := range flags {
+		if size, ok := heapFlag(flag, "-Xmx", "-XX:MaxHeapSize="); ok {
+			if mb, err := parseMemoryMB(size); err != nil || mb != maxMemoryMB {
+				return nil, fmt.Errorf("JVM flag %s conflicts with MaxMemoryMB %d; set the heap size through MaxMemoryMB", flag, maxMemoryMB)
+			}
+			continue
+		}
+		if size, ok := heapFlag(flag, "-Xms", "-XX:InitialHeapSize="); ok {
+			if mb, err := parseMemoryMB(size); err != nil || mb != minMemoryMB {
+				return nil, fmt.Errorf("JVM flag %s conflicts with MinMemoryMB %d; set the heap size through MinMemoryMB", flag, minMemoryMB)
+			}
+			continue
+		}
+		if gc, ok := garbageCollector(flag); ok {
+			if collector != "" && collector != gc {
+				return nil, fmt.Errorf("JVM flags select both %s and %s", collector, gc)
+			}
+			collector = gc
+		}
+
+		key := jvmFlagKey(flag)
+		if i, ok := index[key]; ok {
+			out[i] = flag
+			continue
+		}
+		index[key] = len(out)
+		out = append(out, flag)
+	}
+
+	return append(out,
+		"-Xms"+strconv.Itoa(minMemoryMB)+"M",
+		"-Xmx"+strconv.Itoa(maxMemoryMB)+"M",
+	), nil
+}
+
+func heapFlag(flag string, prefixes ...string) (string, bool) {
+	for _, prefix := range prefixes {
+		if value, ok := strings.CutPrefix(flag, prefix); ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// garbageCollector returns the collector selected by a -XX:+Use...GC flag.
+func garbageCollector(flag string) (string, bool) {
+	name, ok := strings.CutPrefix(flag, "-XX:+Use")
+	if !ok || !strings.HasSuffix(name, "GC") {
+		return "", false
+	}
+	return name, true
+}
+
+// jvmFlagKey identifies a flag independently of its value, so that later
+// flags replace earlier ones with the same name.
+func jvmFlagKey(flag string) string {
+	switch {
+	case strings.HasPrefix(flag, "-XX:+"), strings.HasPrefix(flag, "-XX:-"):
+		return "-XX:" + flag[5:]
+	case strings.HasPrefix(flag, "-XX:"), strings.HasPrefix(flag, "-D"):
+		name, _, _ := strings.Cut(flag, "=")
+		return name
+	case strings.HasPrefix(flag, "-Xss"):
+		return "-Xss"
+	case strings.HasPrefix(flag, "-Xlog:gc"):
+		return "-Xlog:gc"
+	case strings.HasPrefix(flag, "-Xloggc:"):
+		return "-Xloggc:"
+	default:
+		return flag
+	}
+}
+
+// parseMemoryMB converts a JVM memory size such as 4G, 4096m or 4294967296 to
+// megabytes.
+func parseMemoryMB(size string) (int, error) {
+	if size == "" {
+		return 0, fmt.Errorf("empty memory size")
+	}
+	multiplier := 1.0 / (1024 * 1024)
+	switch size[len(size)-1] {
+	case 'k', 'K':
+		multiplier = 1.0 / 1024
+		size = size[:len(size)-1]
+	case 'm', 'M':
+		multiplier = 1
+		size = size[:len(size)-1]
+	case 'g', 'G':
+		multiplier = 1024
+		size = size[:len(size)-1]
+	case 't', 'T':
+		multiplier = 1024 * 1024
+		size = size[:len(size)-1]
+	}
+	value, err := strconv.ParseInt(size, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory size %q: %w", size, err)
+	}
+	return int(float64(value) * multiplier), nil
+}
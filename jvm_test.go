@@ -0,0 +1,130 @@
+package gomcserver
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestParseMemoryMB(t *testing.T) {
+	tests := []struct {
+		size    string
+		want    int
+		wantErr bool
+	}{
+		{"4096m", 4096, false},
+		{"4096M", 4096, false},
+		{"4g", 4096, false},
+		{"4G", 4096, false},
+		{"4194304k", 4096, false},
+		{"4194304K", 4096, false},
+		{"1t", 1024 * 1024, false},
+		{"4294967296", 4096, false},
+		{"", 0, true},
+		{"G", 0, true},
+		{"4x", 0, true},
+		{"4.5G", 0, true},
+		{"-Xmx4G", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseMemoryMB(tt.size)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseMemoryMB(%q) = %d, want an error", tt.size, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseMemoryMB(%q) = %d, %v; want %d", tt.size, got, err, tt.want)
+		}
+	}
+}
+
+func TestFinalizeJvmArgsHeapFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		flags   []string
+		wantErr bool
+	}{
+		{"matching -Xmx and -Xms", []string{"-Xmx4G", "-Xms2048m"}, false},
+		{"matching long forms", []string{"-XX:MaxHeapSize=4096M", "-XX:InitialHeapSize=2g"}, false},
+		{"conflicting -Xmx", []string{"-Xmx8G"}, true},
+		{"conflicting -Xms", []string{"-Xms1G"}, true},
+		{"conflicting MaxHeapSize", []string{"-XX:MaxHeapSize=1024m"}, true},
+		{"malformed -Xmx", []string{"-Xmx4Q"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := NewJvmOptions(JvmPresetNone).Flags(tt.flags...).Build(2048, 4096, 21)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Build = %q, want an error", args)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Build: %v", err)
+			}
+			if want := []string{"-Xms2048M", "-Xmx4096M"}; !reflect.DeepEqual(args, want) {
+				t.Errorf("Build = %q, want %q", args, want)
+			}
+		})
+	}
+}
+
+func TestFinalizeJvmArgsDuplicates(t *testing.T) {
+	args, err := finalizeJvmArgs([]string{
+		"-XX:+UseG1GC",
+		"-XX:+AlwaysPreTouch",
+		"-XX:MaxGCPauseMillis=200",
+		"-Dfoo=1",
+		"-XX:-AlwaysPreTouch",
+		"-XX:MaxGCPauseMillis=100",
+		"-Dfoo=2",
+		"-XX:+UseG1GC",
+	}, 1024, 1024)
+	if err != nil {
+		t.Fatalf("finalizeJvmArgs: %v", err)
+	}
+	want := []string{"-XX:+UseG1GC", "-XX:-AlwaysPreTouch", "-XX:MaxGCPauseMillis=100", "-Dfoo=2", "-Xms1024M", "-Xmx1024M"}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("finalizeJvmArgs = %q, want %q", args, want)
+	}
+
+	if _, err := finalizeJvmArgs([]string{"-XX:+UseG1GC", "-XX:+UseZGC"}, 1024, 1024); err == nil {
+		t.Error("finalizeJvmArgs accepted two garbage collectors")
+	}
+}
+
+func TestGenerationalZGCFlags(t *testing.T) {
+	tests := []struct {
+		javaVersion  int
+		generational bool
+		wantErr      bool
+	}{
+		{0, true, false},
+		{17, false, true},
+		{21, true, false},
+		{22, true, false},
+		{23, false, false},
+		{24, false, false},
+	}
+	for _, tt := range tests {
+		args, err := NewJvmOptions(JvmPresetGenerationalZGC).Build(1024, 1024, tt.javaVersion)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Java %d: Build = %q, want an error", tt.javaVersion, args)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Java %d: Build: %v", tt.javaVersion, err)
+		}
+		if !slices.Contains(args, "-XX:+UseZGC") {
+			t.Errorf("Java %d: Build = %q, want ZGC", tt.javaVersion, args)
+		}
+		if got := slices.Contains(args, "-XX:+ZGenerational"); got != tt.generational {
+			t.Errorf("Java %d: -XX:+ZGenerational present = %v, want %v", tt.javaVersion, got, tt.generational)
+		}
+	}
+}
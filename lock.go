@@ -0,0 +1,90 @@
+package gomcserver
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrDirectoryLocked is returned when another controller manages the server directory.
+var ErrDirectoryLocked = errors.New("server directory is locked by another controller")
+
+func (s *Server) lockPath() string {
+	return filepath.Join(s.Directory, ".mcserverlib", "controller.lock")
+}
+
+// acquireLock claims the server directory for this controller. The claim is an
+// operating system lock on the lock file, so it is released automatically when
+// the controller exits and a lock left behind by a dead controller never needs
+// to be removed. The file also records the owner's PID for error messages.
+func (s *Server) acquireLock() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lockFile != nil {
+		return nil
+	}
+
+	path := s.lockPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create mcserverlib directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open lock file: %w", err)
+	}
+	if err := lockFile(f); err != nil {
+		_ = f.Close()
+		if !errors.Is(err, errLockHeld) {
+			return fmt.Errorf("failed to lock server directory: %w", err)
+		}
+		if owner, readErr := readLockOwner(path); readErr == nil && owner > 0 {
+			return fmt.Errorf("%w (pid %d)", ErrDirectoryLocked, owner)
+		}
+		return ErrDirectoryLocked
+	}
+
+	if err := writeLockOwner(f, os.Getpid()); err != nil {
+		unlockFile(f)
+		_ = f.Close()
+		return fmt.Errorf("failed to write lock file: %w", err)
+	}
+	s.lockFile = f
+	return nil
+}
+
+// releaseLock gives up the directory if this server holds it. The file is kept
+// so that a controller waiting on it never locks an unlinked file.
+func (s *Server) releaseLock() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lockFile == nil {
+		return
+	}
+	_ = writeLockOwner(s.lockFile, 0)
+	unlockFile(s.lockFile)
+	_ = s.lockFile.Close()
+	s.lockFile = nil
+}
+
+func writeLockOwner(f *os.File, pid int) error {
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	value := ""
+	if pid > 0 {
+		value = strconv.Itoa(pid)
+	}
+	_, err := f.WriteAt([]byte(value), 0)
+	return err
+}
+
+func readLockOwner(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
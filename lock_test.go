@@ -0,0 +1,47 @@
+package gomcserver
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLockExcludesSecondController(t *testing.T) {
+	dir := t.TempDir()
+	first := NewServer(dir, "1.21")
+	second := NewServer(dir, "1.21")
+
+	if err := first.acquireLock(); err != nil {
+		t.Fatalf("first acquireLock: %v", err)
+	}
+	err := second.acquireLock()
+	if !errors.Is(err, ErrDirectoryLocked) {
+		t.Fatalf("second acquireLock = %v, want ErrDirectoryLocked", err)
+	}
+	if owner, err := readLockOwner(first.lockPath()); err != nil || owner != os.Getpid() {
+		t.Fatalf("lock owner = %d, %v; want %d", owner, err, os.Getpid())
+	}
+
+	first.releaseLock()
+	if err := second.acquireLock(); err != nil {
+		t.Fatalf("acquireLock after release: %v", err)
+	}
+	second.releaseLock()
+}
+
+func TestLockIgnoresLeftoverFile(t *testing.T) {
+	dir := t.TempDir()
+	s := NewServer(dir, "1.21")
+	if err := os.MkdirAll(filepath.Dir(s.lockPath()), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// An empty or stale lock file without an OS lock behind it belongs to nobody.
+	if err := os.WriteFile(s.lockPath(), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.acquireLock(); err != nil {
+		t.Fatalf("acquireLock: %v", err)
+	}
+	s.releaseLock()
+}
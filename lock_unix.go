@@ -0,0 +1,24 @@
+//go:build !windows
+
+package gomcserver
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// errLockHeld is returned by lockFile when another process holds the lock.
+var errLockHeld = errors.New("lock is held by another process")
+
+func lockFile(f *os.File) error {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return errLockHeld
+	}
+	return err
+}
+
+func unlockFile(f *os.File) {
+	_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,30 @@
+//go:build windows
+
+package gomcserver
+
+import (
+	"errors"
+	"golang.org/x/sys/windows"
+	"os"
+)
+
+// errLockHeld is returned by lockFile when another process holds the lock.
+var errLockHeld = errors.New("lock is held by another process")
+
+// The lock covers a single byte far beyond the PID written at the start of the
+// file, so other controllers can still read the owner.
+const lockOffset = 1 << 30
+
+func lockFile(f *os.File) error {
+	overlapped := &windows.Overlapped{Offset: lockOffset}
+	err := windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, overlapped)
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return errLockHeld
+	}
+	return err
+}
+
+func unlockFile(f *os.File) {
+	overlapped := &windows.Overlapped{Offset: lockOffset}
+	_ = windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, overlapped)
+}
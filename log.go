@@ -0,0 +1,74 @@
+package gomcserver
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// LogEntry is a single parsed line of server output.
+type LogEntry struct {
+	Time    time.Time // timestamp of the line, on the day it was read; zero if absent
+	Thread  string    // e.g. "Server thread"; empty for formats that omit it
+	Level   string    // e.g. "INFO", "WARN", "ERROR"
+	Logger  string    // logger name when the format includes one, e.g. "minecraft/DedicatedServer"
+	Message string    // text after the prefix, or the whole line if it could not be parsed
+	Raw     string    // the original line without its trailing newline
+}
+
+var (
+	// [12:34:56] [Server thread/INFO]: message
+	// [12:34:56] [Server thread/INFO] [minecraft/DedicatedServer]: message
+	vanillaLogPattern = regexp.MustCompile(`^\[(\d{2}:\d{2}:\d{2})\] \[(.+?)/([A-Z]+)\](?: \[([^\]]+)\])?: ?(.*)$`)
+	// [12:34:56 INFO]: message
+	paperLogPattern = regexp.MustCompile(`^\[(\d{2}:\d{2}:\d{2}) ([A-Z]+)\]: ?(.*)$`)
+	ansiPattern     = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+)
+
+// ParseLogLine parses a line in the vanilla/log4j or Paper console format. Lines
+// that match neither format are returned with only Message and Raw set.
+func ParseLogLine(line string) LogEntry {
+	line = strings.TrimRight(line, "\r\n")
+	entry := LogEntry{Raw: line, Message: line}
+	clean := ansiPattern.ReplaceAllString(line, "")
+
+	if m := vanillaLogPattern.FindStringSubmatch(clean); m != nil {
+		entry.Time = parseLogTime(m[1])
+		entry.Thread = m[2]
+		entry.Level = m[3]
+		entry.Logger = m[4]
+		entry.Message = m[5]
+	} else if m := paperLogPattern.FindStringSubmatch(clean); m != nil {
+		entry.Time = parseLogTime(m[1])
+		entry.Level = m[2]
+		entry.Message = m[3]
+	}
+	return entry
+}
+
+// parseLogTime combines an HH:MM:SS clock time with today's local date.
+func parseLogTime(clock string) time.Time {
+	t, err := time.Parse("15:04:05", clock)
+	if err != nil {
+		return time.Time{}
+	}
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), t.Second(), 0, now.Location())
+}
+
+// readLines calls fn for every complete line read from r, and for a trailing
+// partial line at EOF. Lines are passed without their newline.
+func readLines(r io.Reader, fn func(string)) {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			fn(strings.TrimRight(line, "\r\n"))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package gomcserver
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	// chatPattern matches a player chat line such as "<Steve> hello", optionally
+	// prefixed by the "[Not Secure] " marker for unsigned messages.
+	chatPattern = regexp.MustCompile(`^(?:\[Not Secure\] )?<([^>]+)> (.*)$`)
+	// sayPattern matches /say output such as "[Steve] hello" or "[Server] hello".
+	sayPattern = regexp.MustCompile(`^(?:\[Not Secure\] )?\[([^\]]+)\] (.*)$`)
+	// emotePattern matches /me output such as "* Steve waves".
+	emotePattern = regexp.MustCompile(`^(?:\[Not Secure\] )?\* (\S+) (.*)$`)
+	// advancementPattern matches "Steve has made the advancement [Stone Age]" and
+	// the challenge/goal variants.
+	advancementPattern = regexp.MustCompile(`^(\S+) has (?:made the advancement|completed the challenge|reached the goal) \[(.+)\]$`)
+	// commandPattern matches "Steve issued server command: /gamemode creative",
+	// printed by Bukkit-based servers.
+	commandPattern = regexp.MustCompile(`^(\S+) issued server command: (.*)$`)
+	// playerNamePattern matches a valid Java Edition player name.
+	playerNamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{1,16}$`)
+)
+
+// AdvancementKind distinguishes the three advancement frames.
+type AdvancementKind string
+
+const (
+	AdvancementTask      AdvancementKind = "advancement"
+	AdvancementChallenge AdvancementKind = "challenge"
+	AdvancementGoal      AdvancementKind = "goal"
+)
+
+// deathMessages is the vanilla death message catalogue with the leading player
+// name removed. Entries are matched longest first; any text after the phrase
+// names the killer (and optionally the weapon).
+var deathMessages = []string{
+	"was shot by",
+	"was pummeled by",
+	"was pricked to death",
+	"walked into a cactus while trying to escape",
+	"walked into a cactus whilst trying to escape",
+	"drowned",
+	"died from dehydration",
+	"experienced kinetic energy",
+	"blew up",
+	"was blown up by",
+	"was killed by",
+	"hit the ground too hard",
+	"fell from a high place",
+	"fell off a ladder",
+	"fell off some vines",
+	"fell off some weeping vines",
+	"fell off some twisting vines",
+	"fell off scaffolding",
+	"fell while climbing",
+	"was doomed to fall",
+	"fell too far and was finished by",
+	"was struck by lightning",
+	"went up in flames",
+	"walked into fire",
+	"burned to death",
+	"was burned to a crisp",
+	"went off with a bang",
+	"tried to swim in lava",
+	"discovered the floor was lava",
+	"walked into the danger zone due to",
+	"was killed by magic",
+	"was killed by even more magic",
+	"was killed while trying to hurt",
+	"was killed trying to hurt",
+	"froze to death",
+	"was frozen to death by",
+	"was slain by",
+	"was smashed by",
+	"was fireballed by",
+	"was stung to death",
+	"was shot by a skull from",
+	"starved to death",
+	"suffocated in a wall",
+	"was squished too much",
+	"was squashed by",
+	"was poked to death by a sweet berry bush",
+	"was impaled by",
+	"was impaled on a stalagmite",
+	"was skewered by a falling stalactite",
+	"was squashed by a falling anvil",
+	"was squashed by a falling block",
+	"fell out of the world",
+	"didn't want to live in the same world as",
+	"left the confines of this world",
+	"withered away",
+	"was roasted in dragon's breath",
+	"was obliterated by a sonically-charged shriek",
+	"was speared by",
+	"was killed",
+	"died",
+}
+
+// killerPrefixes introduce the killer in death messages that name one after the
+// base phrase, e.g. "drowned whilst trying to escape Zombie".
+var killerPrefixes = []string{
+	" whilst trying to escape ",
+	" while trying to escape ",
+	" whilst fighting ",
+	" while fighting ",
+	" by ",
+	" due to ",
+}
+
+func init() {
+	sort.SliceStable(deathMessages, func(i, j int) bool {
+		return len(deathMessages[i]) > len(deathMessages[j])
+	})
+}
+
+// parseGameMessage turns a log message into a gameplay event, or returns nil if
+// the message is not recognised. online lists the players currently connected.
+func parseGameMessage(message string, online []string) Event {
+	if m := chatPattern.FindStringSubmatch(message); m != nil {
+		return ChatMessage{Player: m[1], Message: m[2]}
+	}
+	if m := emotePattern.FindStringSubmatch(message); m != nil {
+		return EmoteMessage{Player: m[1], Action: m[2]}
+	}
+	if m := sayPattern.FindStringSubmatch(message); m != nil && isSayer(m[1], online) {
+		return SayMessage{Sender: m[1], Message: m[2]}
+	}
+	if m := advancementPattern.FindStringSubmatch(message); m != nil {
+		return AdvancementMade{Player: m[1], Advancement: m[2], Kind: advancementKind(message)}
+	}
+	if m := commandPattern.FindStringSubmatch(message); m != nil {
+		return CommandIssued{Player: m[1], Command: m[2]}
+	}
+	if death, ok := parseDeath(message, online); ok {
+		return death
+	}
+	return nil
+}
+
+// isSayer reports whether a bracketed prefix can be a /say sender. Plugin log
+// lines share the "[Name] text" shape, so only the console, RCON and online
+// players are accepted.
+func isSayer(name string, online []string) bool {
+	return name == "Server" || name == "Rcon" || isOnline(name, online)
+}
+
+func isOnline(name string, online []string) bool {
+	for _, player := range online {
+		if player == name {
+			return true
+		}
+	}
+	return false
+}
+
+func advancementKind(message string) AdvancementKind {
+	switch {
+	case strings.Contains(message, " has completed the challenge "):
+		return AdvancementChallenge
+	case strings.Contains(message, " has reached the goal "):
+		return AdvancementGoal
+	default:
+		return AdvancementTask
+	}
+}
+
+// parseDeath matches a message against the death message catalogue. Plugin
+// and mod output can share the shape of a death message, so only online
+// players are accepted.
+func parseDeath(message string, online []string) (PlayerDied, bool) {
+	player, rest, ok := strings.Cut(message, " ")
+	if !ok || !playerNamePattern.MatchString(player) || !isOnline(player, online) {
+		return PlayerDied{}, false
+	}
+
+	for _, phrase := range deathMessages {
+		if !strings.HasPrefix(rest, phrase) {
+			continue
+		}
+		tail := rest[len(phrase):]
+		if tail != "" && tail[0] != ' ' {
+			continue
+		}
+
+		death := PlayerDied{Player: player, Message: message}
+		if strings.HasSuffix(phrase, " by") || strings.HasSuffix(phrase, " to") ||
+			strings.HasSuffix(phrase, " escape") || strings.HasSuffix(phrase, " hurt") ||
+			strings.HasSuffix(phrase, " as") || strings.HasSuffix(phrase, " from") {
+			death.Killer = strings.TrimSpace(tail)
+		} else {
+			for _, prefix := range killerPrefixes {
+				if _, killer, found := strings.Cut(tail, prefix); found {
+					death.Killer = killer
+					break
+				}
+			}
+		}
+		if killer, weapon, found := strings.Cut(death.Killer, " using "); found {
+			death.Killer = killer
+			death.Weapon = strings.Trim(weapon, "[]")
+		}
+		return death, true
+	}
+	return PlayerDied{}, false
+}
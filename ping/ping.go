@@ -0,0 +1,373 @@
+// Package ping implements the Minecraft Server List Ping protocol, both the
+// modern handshake/status exchange and the legacy 0xFE ping.
+package ping
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// maxPacketLength bounds incoming packets (the status JSON may embed a favicon).
+const maxPacketLength = 1 << 21
+
+// Status is the response to a modern status request.
+type Status struct {
+	Version struct {
+		Name     string `json:"name"`
+		Protocol int    `json:"protocol"`
+	} `json:"version"`
+	Players struct {
+		Max    int            `json:"max"`
+		Online int            `json:"online"`
+		Sample []PlayerSample `json:"sample"`
+	} `json:"players"`
+	Description        ChatComponent `json:"description"`
+	Favicon            string        `json:"favicon"` // data:image/png;base64,... URI
+	EnforcesSecureChat bool          `json:"enforcesSecureChat"`
+
+	Latency time.Duration   `json:"-"` // round trip of the ping/pong exchange
+	Raw     json.RawMessage `json:"-"` // the status JSON as sent by the server
+}
+
+// PlayerSample is an entry in the status player sample.
+type PlayerSample struct {
+	Name string `json:"name"`
+	ID   string `json:"id"`
+}
+
+// MOTD returns the message of the day as plain text.
+func (s *Status) MOTD() string {
+	return s.Description.PlainText()
+}
+
+// ChatComponent is a JSON text component. The server may send it as a plain
+// string, an object or an array of components.
+type ChatComponent struct {
+	Text      string          `json:"text"`
+	Translate string          `json:"translate,omitempty"`
+	Color     string          `json:"color,omitempty"`
+	Bold      bool            `json:"bold,omitempty"`
+	Italic    bool            `json:"italic,omitempty"`
+	Extra     []ChatComponent `json:"extra,omitempty"`
+}
+
+// UnmarshalJSON accepts the string, object and array forms of a component.
+func (c *ChatComponent) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	switch {
+	case len(data) == 0 || bytes.Equal(data, []byte("null")):
+		*c = ChatComponent{}
+		return nil
+	case data[0] == '"':
+		var text string
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+		*c = ChatComponent{Text: text}
+		return nil
+	case data[0] == '[':
+		var parts []ChatComponent
+		if err := json.Unmarshal(data, &parts); err != nil {
+			return err
+		}
+		*c = ChatComponent{Extra: parts}
+		return nil
+	default:
+		type plain ChatComponent
+		var p plain
+		if err := json.Unmarshal(data, &p); err != nil {
+			return err
+		}
+		*c = ChatComponent(p)
+		return nil
+	}
+}
+
+// PlainText returns the text of the component and its children with legacy
+// § formatting codes removed.
+func (c ChatComponent) PlainText() string {
+	var b strings.Builder
+	c.writeText(&b)
+	return StripFormatting(b.String())
+}
+
+func (c ChatComponent) writeText(b *strings.Builder) {
+	if c.Text != "" {
+		b.WriteString(c.Text)
+	} else {
+		b.WriteString(c.Translate)
+	}
+	for _, extra := range c.Extra {
+		extra.writeText(b)
+	}
+}
+
+// StripFormatting removes legacy § colour and style codes from s.
+func StripFormatting(s string) string {
+	if !strings.ContainsRune(s, '§') {
+		return s
+	}
+	var b strings.Builder
+	skip := false
+	for _, r := range s {
+		if skip {
+			skip = false
+			continue
+		}
+		if r == '§' {
+			skip = true
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Ping performs a modern (1.7+) status request against addr ("host:port") and
+// measures the round-trip latency.
+func Ping(ctx context.Context, addr string) (*Status, error) {
+	host, port, err := splitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, closeConn, err := dial(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	defer closeConn()
+	r := bufio.NewReader(conn)
+
+	// Handshake with next state 1 (status), followed by a status request.
+	handshake := &bytes.Buffer{}
+	writeVarInt(handshake, 0x00)
+	writeVarInt(handshake, -1)
+	writeString(handshake, host)
+	_ = binary.Write(handshake, binary.BigEndian, port)
+	writeVarInt(handshake, 1)
+	if err := writePacket(conn, handshake.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := writePacket(conn, []byte{0x00}); err != nil {
+		return nil, err
+	}
+
+	payload, err := readPacket(r)
+	if err != nil {
+		return nil, err
+	}
+	body := bytes.NewReader(payload)
+	if id, err := readVarInt(body); err != nil || id != 0x00 {
+		return nil, fmt.Errorf("ping: unexpected status packet id %d", id)
+	}
+	raw, err := readString(body)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &Status{Raw: json.RawMessage(raw)}
+	if err := json.Unmarshal([]byte(raw), status); err != nil {
+		return nil, fmt.Errorf("ping: invalid status JSON: %w", err)
+	}
+
+	// Ping/pong to measure latency. Some servers close the connection instead of
+	// answering, in which case the status is still returned.
+	token := rand.Int63()
+	pingPacket := &bytes.Buffer{}
+	writeVarInt(pingPacket, 0x01)
+	_ = binary.Write(pingPacket, binary.BigEndian, token)
+	sent := time.Now()
+	if err := writePacket(conn, pingPacket.Bytes()); err != nil {
+		return status, nil
+	}
+	pong, err := readPacket(r)
+	if err != nil {
+		return status, nil
+	}
+	pongBody := bytes.NewReader(pong)
+	var echoed int64
+	if id, err := readVarInt(pongBody); err == nil && id == 0x01 &&
+		binary.Read(pongBody, binary.BigEndian, &echoed) == nil && echoed == token {
+		status.Latency = time.Since(sent)
+	}
+	return status, nil
+}
+
+// LegacyStatus is the response to a legacy (pre-1.7) ping.
+type LegacyStatus struct {
+	Protocol int    // -1 for servers older than 1.4
+	Version  string // empty for servers older than 1.4
+	MOTD     string
+	Online   int
+	Max      int
+}
+
+// PingLegacy sends the 1.4–1.6 0xFE 0x01 ping to addr. It also understands the
+// reply format of servers older than 1.4.
+func PingLegacy(ctx context.Context, addr string) (*LegacyStatus, error) {
+	conn, closeConn, err := dial(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	defer closeConn()
+
+	if _, err := conn.Write([]byte{0xFE, 0x01}); err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReader(conn)
+	id, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if id != 0xFF {
+		return nil, fmt.Errorf("ping: unexpected legacy packet id 0x%X", id)
+	}
+	var length uint16
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return nil, err
+	}
+	units := make([]uint16, length)
+	if err := binary.Read(r, binary.BigEndian, units); err != nil {
+		return nil, err
+	}
+	return parseLegacy(string(utf16.Decode(units)))
+}
+
+func parseLegacy(s string) (*LegacyStatus, error) {
+	if strings.HasPrefix(s, "§1\x00") {
+		fields := strings.Split(s, "\x00")
+		if len(fields) < 6 {
+			return nil, errors.New("ping: malformed legacy response")
+		}
+		protocol, _ := strconv.Atoi(fields[1])
+		online, _ := strconv.Atoi(fields[4])
+		maxPlayers, _ := strconv.Atoi(fields[5])
+		return &LegacyStatus{Protocol: protocol, Version: fields[2], MOTD: fields[3], Online: online, Max: maxPlayers}, nil
+	}
+
+	fields := strings.Split(s, "§")
+	if len(fields) < 3 {
+		return nil, errors.New("ping: malformed legacy response")
+	}
+	n := len(fields)
+	online, _ := strconv.Atoi(fields[n-2])
+	maxPlayers, _ := strconv.Atoi(fields[n-1])
+	return &LegacyStatus{Protocol: -1, MOTD: strings.Join(fields[:n-2], "§"), Online: online, Max: maxPlayers}, nil
+}
+
+// --- Wire helpers ---
+
+// dial connects to addr and ties the connection's deadline to ctx. The returned
+// function closes the connection.
+func dial(ctx context.Context, addr string) (net.Conn, func(), error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ping: dial failed: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Unix(1, 0))
+	})
+	return conn, func() {
+		stop()
+		_ = conn.Close()
+	}, nil
+}
+
+func splitHostPort(addr string) (string, uint16, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", 0, fmt.Errorf("ping: invalid address %q: %w", addr, err)
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return "", 0, fmt.Errorf("ping: invalid port %q: %w", portStr, err)
+	}
+	return host, uint16(port), nil
+}
+
+func writeVarInt(w *bytes.Buffer, value int32) {
+	v := uint32(value)
+	for {
+		if v&^0x7F == 0 {
+			w.WriteByte(byte(v))
+			return
+		}
+		w.WriteByte(byte(v&0x7F | 0x80))
+		v >>= 7
+	}
+}
+
+func readVarInt(r io.ByteReader) (int32, error) {
+	var result uint32
+	for i := 0; i < 5; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		result |= uint32(b&0x7F) << (7 * i)
+		if b&0x80 == 0 {
+			return int32(result), nil
+		}
+	}
+	return 0, errors.New("ping: VarInt is too big")
+}
+
+func writeString(w *bytes.Buffer, s string) {
+	writeVarInt(w, int32(len(s)))
+	w.WriteString(s)
+}
+
+func readString(r *bytes.Reader) (string, error) {
+	length, err := readVarInt(r)
+	if err != nil {
+		return "", err
+	}
+	if length < 0 || int(length) > r.Len() {
+		return "", fmt.Errorf("ping: invalid string length %d", length)
+	}
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+func writePacket(w io.Writer, payload []byte) error {
+	packet := &bytes.Buffer{}
+	writeVarInt(packet, int32(len(payload)))
+	packet.Write(payload)
+	_, err := w.Write(packet.Bytes())
+	return err
+}
+
+func readPacket(r *bufio.Reader) ([]byte, error) {
+	length, err := readVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	if length <= 0 || length > maxPacketLength {
+		return nil, fmt.Errorf("ping: invalid packet length %d", length)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
@@ -0,0 +1,159 @@
+package ping
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+// serveStatus answers a single modern status exchange with statusJSON and
+// echoes the ping payload.
+func serveStatus(t *testing.T, statusJSON string) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+
+		handshake, err := readPacket(r)
+		if err != nil {
+			return
+		}
+		body := bytes.NewReader(handshake)
+		if id, _ := readVarInt(body); id != 0x00 {
+			return
+		}
+		_, _ = readVarInt(body) // protocol version
+		_, _ = readString(body) // host
+		var port uint16
+		_ = binary.Read(body, binary.BigEndian, &port)
+		if next, _ := readVarInt(body); next != 1 {
+			return
+		}
+		if _, err := readPacket(r); err != nil { // status request
+			return
+		}
+
+		response := &bytes.Buffer{}
+		writeVarInt(response, 0x00)
+		writeString(response, statusJSON)
+		_ = writePacket(conn, response.Bytes())
+
+		ping, err := readPacket(r)
+		if err != nil {
+			return
+		}
+		_ = writePacket(conn, ping)
+	}()
+	return l.Addr().String()
+}
+
+func TestPing(t *testing.T) {
+	addr := serveStatus(t, `{
+		"version": {"name": "1.21.4", "protocol": 769},
+		"players": {"max": 20, "online": 1, "sample": [{"name": "Steve", "id": "8667ba71-b85a-4004-af54-457a9734eed7"}]},
+		"description": {"text": "§aHello ", "extra": ["world", {"text": "!"}]},
+		"enforcesSecureChat": true
+	}`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	status, err := Ping(ctx, addr)
+	if err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+	if status.Version.Name != "1.21.4" || status.Version.Protocol != 769 {
+		t.Errorf("Version = %+v", status.Version)
+	}
+	if status.Players.Max != 20 || status.Players.Online != 1 || len(status.Players.Sample) != 1 || status.Players.Sample[0].Name != "Steve" {
+		t.Errorf("Players = %+v", status.Players)
+	}
+	if got := status.MOTD(); got != "Hello world!" {
+		t.Errorf("MOTD = %q, want %q", got, "Hello world!")
+	}
+	if !status.EnforcesSecureChat {
+		t.Error("EnforcesSecureChat = false")
+	}
+	if status.Latency <= 0 {
+		t.Errorf("Latency = %v, want the measured round trip", status.Latency)
+	}
+}
+
+func TestPingStringDescription(t *testing.T) {
+	addr := serveStatus(t, `{"version": {"name": "1.8.9", "protocol": 47}, "players": {"max": 10, "online": 0}, "description": "A Minecraft Server"}`)
+
+	status, err := Ping(context.Background(), addr)
+	if err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+	if got := status.MOTD(); got != "A Minecraft Server" {
+		t.Errorf("MOTD = %q", got)
+	}
+}
+
+func TestPingInvalidJSON(t *testing.T) {
+	addr := serveStatus(t, `{"version":`)
+
+	if _, err := Ping(context.Background(), addr); err == nil {
+		t.Fatal("Ping succeeded with invalid status JSON")
+	}
+}
+
+func TestVarIntRoundTrip(t *testing.T) {
+	cases := map[int32][]byte{
+		0:          {0x00},
+		1:          {0x01},
+		127:        {0x7f},
+		128:        {0x80, 0x01},
+		255:        {0xff, 0x01},
+		25565:      {0xdd, 0xc7, 0x01},
+		2147483647: {0xff, 0xff, 0xff, 0xff, 0x07},
+		-1:         {0xff, 0xff, 0xff, 0xff, 0x0f},
+	}
+	for value, encoded := range cases {
+		buf := &bytes.Buffer{}
+		writeVarInt(buf, value)
+		if !bytes.Equal(buf.Bytes(), encoded) {
+			t.Errorf("writeVarInt(%d) = % x, want % x", value, buf.Bytes(), encoded)
+		}
+		got, err := readVarInt(bytes.NewReader(encoded))
+		if err != nil || got != value {
+			t.Errorf("readVarInt(% x) = %d, %v, want %d", encoded, got, err, value)
+		}
+	}
+
+	if _, err := readVarInt(bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0x01})); err == nil {
+		t.Error("readVarInt accepted a VarInt longer than 5 bytes")
+	}
+}
+
+func TestParseLegacy(t *testing.T) {
+	status, err := parseLegacy("§1\x0047\x001.4.2\x00A Minecraft Server\x003\x0020")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Protocol != 47 || status.Version != "1.4.2" || status.MOTD != "A Minecraft Server" || status.Online != 3 || status.Max != 20 {
+		t.Errorf("parseLegacy = %+v", status)
+	}
+
+	old, err := parseLegacy("Old server§2§10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if old.Protocol != -1 || old.MOTD != "Old server" || old.Online != 2 || old.Max != 10 {
+		t.Errorf("parseLegacy (pre-1.4) = %+v", old)
+	}
+}
@@ -0,0 +1,114 @@
+package gomcserver
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+)
+
+// Launcher starts server processes. ExecLauncher, which runs a real JVM, is
+// used unless StartOptions.Launcher is set; the gomcservertest package provides
+// a scriptable fake for tests.
+type Launcher interface {
+	// Launch starts the process described by spec. The process must be running
+	// when Launch returns.
+	Launch(spec ProcessSpec) (Process, error)
+}
+
+// ProcessSpec describes the server process to launch.
+type ProcessSpec struct {
+	Path     string    // executable, usually the java binary
+	Args     []string  // arguments, not including Path
+	Dir      string    // working directory, the server directory
+	Detached bool      // the process should outlive the controller and not use pipes
+	Stdin    io.Reader // console input to use instead of a pipe, or nil
+}
+
+// Process is a running server process.
+type Process interface {
+	// Pid returns the operating system process ID, or 0 if there is none.
+	Pid() int
+	// Stdin returns the console pipe, or nil if the process reads its console
+	// from ProcessSpec.Stdin or has none.
+	Stdin() io.WriteCloser
+	// Stdout and Stderr return the output pipes, or nil for detached processes.
+	Stdout() io.Reader
+	Stderr() io.Reader
+	Signal(sig os.Signal) error
+	Kill() error
+	// Wait blocks until the process exits. A non-zero exit is reported as an
+	// error with an ExitCode() int method, like *exec.ExitError.
+	Wait() error
+}
+
+// ExecLauncher launches processes with os/exec.
+type ExecLauncher struct{}
+
+// Launch starts spec as an operating system process.
+func (ExecLauncher) Launch(spec ProcessSpec) (Process, error) {
+	cmd := exec.Command(spec.Path, spec.Args...)
+	cmd.Dir = spec.Dir
+	p := &execProcess{cmd: cmd}
+
+	if spec.Detached {
+		configureDetached(cmd)
+	} else {
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get stdout pipe: %w", err)
+		}
+		stderr, err := cmd.StderrPipe()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get stderr pipe: %w", err)
+		}
+		p.stdout, p.stderr = stdout, stderr
+	}
+
+	if spec.Stdin != nil {
+		cmd.Stdin = spec.Stdin
+	} else if !spec.Detached {
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get stdin pipe: %w", err)
+		}
+		p.stdin = stdin
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+type execProcess struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.Reader
+	stderr io.Reader
+}
+
+func (p *execProcess) Pid() int                   { return p.cmd.Process.Pid }
+func (p *execProcess) Stdin() io.WriteCloser      { return p.stdin }
+func (p *execProcess) Stdout() io.Reader          { return p.stdout }
+func (p *execProcess) Stderr() io.Reader          { return p.stderr }
+func (p *execProcess) Signal(sig os.Signal) error { return p.cmd.Process.Signal(sig) }
+func (p *execProcess) Kill() error                { return p.cmd.Process.Kill() }
+func (p *execProcess) Wait() error                { return p.cmd.Wait() }
+
+// attachedProcess is a process started by an earlier controller. It can be
+// signalled, but its output and exit status are not available.
+type attachedProcess struct {
+	proc *os.Process
+}
+
+func (p *attachedProcess) Pid() int                   { return p.proc.Pid }
+func (p *attachedProcess) Stdin() io.WriteCloser      { return nil }
+func (p *attachedProcess) Stdout() io.Reader          { return nil }
+func (p *attachedProcess) Stderr() io.Reader          { return nil }
+func (p *attachedProcess) Signal(sig os.Signal) error { return p.proc.Signal(sig) }
+func (p *attachedProcess) Kill() error                { return p.proc.Kill() }
+func (p *attachedProcess) Wait() error {
+	return errors.New("cannot wait for a process started by another controller")
+}
@@ -0,0 +1,241 @@
+// Package query implements the GameSpy4 UDP query protocol that Minecraft
+// servers expose when enable-query is set in server.properties.
+package query
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	typeHandshake byte = 0x09
+	typeStat      byte = 0x00
+
+	maxResponseSize = 64 * 1024
+)
+
+var (
+	magic = []byte{0xFE, 0xFD}
+	// fullStatPadding precedes the key/value section of a full stat response.
+	fullStatPadding = []byte("splitnum\x00\x80\x00")
+	// playerPadding precedes the player list of a full stat response.
+	playerPadding = []byte("\x01player_\x00\x00")
+)
+
+// BasicStat is the response to a basic stat request.
+type BasicStat struct {
+	MOTD       string
+	GameType   string
+	Map        string
+	NumPlayers int
+	MaxPlayers int
+	HostPort   int
+	HostIP     string
+}
+
+// QueryResult is the response to a full stat request.
+type QueryResult struct {
+	MOTD       string
+	GameType   string
+	GameID     string
+	Version    string
+	ServerMod  string   // server software reported in the plugins field, e.g. "Paper on 1.21"
+	Plugins    []string // plugin names with versions, as reported by the server
+	Map        string
+	NumPlayers int
+	MaxPlayers int
+	HostPort   int
+	HostIP     string
+	Players    []string
+	Raw        map[string]string // every key/value pair sent by the server
+}
+
+// Basic performs a handshake followed by a basic stat request against addr.
+func Basic(ctx context.Context, addr string) (*BasicStat, error) {
+	conn, closeConn, session, token, err := handshake(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	defer closeConn()
+
+	payload, err := roundTrip(conn, typeStat, session, tokenBytes(token))
+	if err != nil {
+		return nil, contextErr(ctx, err)
+	}
+
+	fields := bytes.SplitN(payload, []byte{0}, 6)
+	if len(fields) < 6 || len(fields[5]) < 2 {
+		return nil, errors.New("query: malformed basic stat response")
+	}
+	stat := &BasicStat{
+		MOTD:     string(fields[0]),
+		GameType: string(fields[1]),
+		Map:      string(fields[2]),
+		HostPort: int(binary.LittleEndian.Uint16(fields[5][:2])),
+		HostIP:   strings.TrimRight(string(fields[5][2:]), "\x00"),
+	}
+	stat.NumPlayers, _ = strconv.Atoi(string(fields[3]))
+	stat.MaxPlayers, _ = strconv.Atoi(string(fields[4]))
+	return stat, nil
+}
+
+// Full performs a handshake followed by a full stat request against addr.
+func Full(ctx context.Context, addr string) (*QueryResult, error) {
+	conn, closeConn, session, token, err := handshake(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	defer closeConn()
+
+	request := append(tokenBytes(token), 0, 0, 0, 0)
+	payload, err := roundTrip(conn, typeStat, session, request)
+	if err != nil {
+		return nil, contextErr(ctx, err)
+	}
+	return parseFullStat(payload)
+}
+
+func parseFullStat(payload []byte) (*QueryResult, error) {
+	if !bytes.HasPrefix(payload, fullStatPadding) {
+		return nil, errors.New("query: malformed full stat response")
+	}
+	payload = payload[len(fullStatPadding):]
+
+	kv, players, found := bytes.Cut(payload, playerPadding)
+	if !found {
+		return nil, errors.New("query: full stat response has no player section")
+	}
+
+	raw := make(map[string]string)
+	parts := bytes.Split(kv, []byte{0})
+	for i := 0; i+1 < len(parts); i += 2 {
+		key := string(parts[i])
+		if key == "" {
+			break
+		}
+		raw[key] = string(parts[i+1])
+	}
+
+	result := &QueryResult{
+		MOTD:     raw["hostname"],
+		GameType: raw["gametype"],
+		GameID:   raw["game_id"],
+		Version:  raw["version"],
+		Map:      raw["map"],
+		HostIP:   raw["hostip"],
+		Players:  []string{},
+		Raw:      raw,
+	}
+	result.NumPlayers, _ = strconv.Atoi(raw["numplayers"])
+	result.MaxPlayers, _ = strconv.Atoi(raw["maxplayers"])
+	result.HostPort, _ = strconv.Atoi(raw["hostport"])
+	result.ServerMod, result.Plugins = parsePlugins(raw["plugins"])
+
+	for _, name := range bytes.Split(players, []byte{0}) {
+		if len(name) == 0 {
+			break
+		}
+		result.Players = append(result.Players, string(name))
+	}
+	return result, nil
+}
+
+// parsePlugins splits the plugins field, formatted as
+// "<server mod>: <plugin> <version>; <plugin> <version>".
+func parsePlugins(value string) (string, []string) {
+	plugins := []string{}
+	if value == "" {
+		return "", plugins
+	}
+	mod, list, found := strings.Cut(value, ":")
+	if !found {
+		return strings.TrimSpace(value), plugins
+	}
+	for _, plugin := range strings.Split(list, ";") {
+		if plugin = strings.TrimSpace(plugin); plugin != "" {
+			plugins = append(plugins, plugin)
+		}
+	}
+	return strings.TrimSpace(mod), plugins
+}
+
+// handshake opens a UDP socket to addr and obtains a challenge token. The
+// socket's deadline follows ctx, and cancelling ctx interrupts blocked reads.
+// The returned function closes the socket.
+func handshake(ctx context.Context, addr string) (net.Conn, func(), int32, int32, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", addr)
+	if err != nil {
+		return nil, nil, 0, 0, fmt.Errorf("query: dial failed: %w", err)
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(5 * time.Second)
+	}
+	_ = conn.SetDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Now())
+	})
+	closeConn := func() {
+		stop()
+		_ = conn.Close()
+	}
+
+	session := rand.Int31() & 0x0F0F0F0F
+	payload, err := roundTrip(conn, typeHandshake, session, nil)
+	if err != nil {
+		closeConn()
+		return nil, nil, 0, 0, contextErr(ctx, err)
+	}
+	token, err := strconv.ParseInt(strings.TrimRight(string(payload), "\x00"), 10, 64)
+	if err != nil {
+		closeConn()
+		return nil, nil, 0, 0, fmt.Errorf("query: invalid challenge token: %w", err)
+	}
+	return conn, closeConn, session, int32(token), nil
+}
+
+// contextErr prefers ctx's error over the I/O error it caused.
+func contextErr(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+// roundTrip sends a request and returns the payload of the matching response.
+func roundTrip(conn net.Conn, packetType byte, session int32, body []byte) ([]byte, error) {
+	request := make([]byte, 0, 7+len(body))
+	request = append(request, magic...)
+	request = append(request, packetType)
+	request = binary.BigEndian.AppendUint32(request, uint32(session))
+	request = append(request, body...)
+	if _, err := conn.Write(request); err != nil {
+		return nil, fmt.Errorf("query: write failed: %w", err)
+	}
+
+	buf := make([]byte, maxResponseSize)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, fmt.Errorf("query: read failed: %w", err)
+		}
+		if n < 5 || buf[0] != packetType || int32(binary.BigEndian.Uint32(buf[1:5])) != session {
+			continue
+		}
+		return append([]byte(nil), buf[5:n]...), nil
+	}
+}
+
+func tokenBytes(token int32) []byte {
+	return binary.BigEndian.AppendUint32(nil, uint32(token))
+}
@@ -0,0 +1,272 @@
+package query
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const fakeToken = 9513307
+
+// fakeServer answers query requests on a local UDP socket the way a vanilla
+// server does. challenge replaces the handshake payload and strayFirst sends a
+// response for another session ahead of each real one.
+type fakeServer struct {
+	conn       net.PacketConn
+	challenge  string
+	strayFirst bool
+	strayOnly  bool
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return &fakeServer{conn: conn, challenge: "9513307\x00"}
+}
+
+func (f *fakeServer) addr() string { return f.conn.LocalAddr().String() }
+
+func (f *fakeServer) serve() {
+	buf := make([]byte, 1500)
+	for {
+		n, from, err := f.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		if n < 7 || !bytes.Equal(buf[:2], magic) {
+			continue
+		}
+		packetType, session := buf[2], binary.BigEndian.Uint32(buf[3:7])
+		body := buf[7:n]
+
+		var payload []byte
+		switch packetType {
+		case typeHandshake:
+			payload = []byte(f.challenge)
+		case typeStat:
+			if len(body) < 4 || int32(binary.BigEndian.Uint32(body)) != fakeToken {
+				continue // vanilla ignores requests with a wrong token
+			}
+			if len(body) == 8 {
+				payload = fullStatPayload("Steve", "Alex")
+			} else {
+				payload = basicStatPayload()
+			}
+		default:
+			continue
+		}
+		if f.strayFirst || f.strayOnly {
+			_, _ = f.conn.WriteTo(response(packetType, session+1, payload), from)
+		}
+		if !f.strayOnly {
+			_, _ = f.conn.WriteTo(response(packetType, session, payload), from)
+		}
+	}
+}
+
+func response(packetType byte, session uint32, payload []byte) []byte {
+	packet := binary.BigEndian.AppendUint32([]byte{packetType}, session)
+	return append(packet, payload...)
+}
+
+func basicStatPayload() []byte {
+	var b bytes.Buffer
+	b.WriteString("A Minecraft Server\x00SMP\x00world\x002\x0020\x00")
+	b.Write(binary.LittleEndian.AppendUint16(nil, 25565))
+	b.WriteString("127.0.0.1\x00")
+	return b.Bytes()
+}
+
+func fullStatPayload(players ...string) []byte {
+	var b bytes.Buffer
+	b.Write(fullStatPadding)
+	for _, kv := range [][2]string{
+		{"hostname", "A Minecraft Server"},
+		{"gametype", "SMP"},
+		{"game_id", "MINECRAFT"},
+		{"version", "1.21.1"},
+		{"plugins", "Paper on 1.21.1: WorldEdit 7.3.0; LuckPerms 5.4"},
+		{"map", "world"},
+		{"numplayers", "2"},
+		{"maxplayers", "20"},
+		{"hostport", "25565"},
+		{"hostip", "127.0.0.1"},
+	} {
+		b.WriteString(kv[0] + "\x00" + kv[1] + "\x00")
+	}
+	b.WriteByte(0)
+	b.Write(playerPadding)
+	for _, player := range players {
+		b.WriteString(player + "\x00")
+	}
+	b.WriteByte(0)
+	return b.Bytes()
+}
+
+func testContext(t *testing.T, timeout time.Duration) context.Context {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestBasic(t *testing.T) {
+	srv := newFakeServer(t)
+	go srv.serve()
+
+	stat, err := Basic(testContext(t, 2*time.Second), srv.addr())
+	if err != nil {
+		t.Fatalf("Basic: %v", err)
+	}
+	want := &BasicStat{
+		MOTD:       "A Minecraft Server",
+		GameType:   "SMP",
+		Map:        "world",
+		NumPlayers: 2,
+		MaxPlayers: 20,
+		HostPort:   25565,
+		HostIP:     "127.0.0.1",
+	}
+	if !reflect.DeepEqual(stat, want) {
+		t.Errorf("Basic = %+v, want %+v", stat, want)
+	}
+}
+
+func TestFull(t *testing.T) {
+	srv := newFakeServer(t)
+	go srv.serve()
+
+	result, err := Full(testContext(t, 2*time.Second), srv.addr())
+	if err != nil {
+		t.Fatalf("Full: %v", err)
+	}
+	if result.MOTD != "A Minecraft Server" || result.Version != "1.21.1" || result.NumPlayers != 2 || result.HostPort != 25565 {
+		t.Errorf("Full = %+v", result)
+	}
+	if !reflect.DeepEqual(result.Players, []string{"Steve", "Alex"}) {
+		t.Errorf("Players = %q, want Steve and Alex", result.Players)
+	}
+	if result.ServerMod != "Paper on 1.21.1" || !reflect.DeepEqual(result.Plugins, []string{"WorldEdit 7.3.0", "LuckPerms 5.4"}) {
+		t.Errorf("ServerMod, Plugins = %q, %q", result.ServerMod, result.Plugins)
+	}
+}
+
+func TestInvalidChallengeToken(t *testing.T) {
+	srv := newFakeServer(t)
+	srv.challenge = "not a number\x00"
+	go srv.serve()
+
+	_, err := Full(testContext(t, 2*time.Second), srv.addr())
+	if err == nil || !strings.Contains(err.Error(), "invalid challenge token") {
+		t.Fatalf("Full error = %v, want an invalid challenge token", err)
+	}
+}
+
+func TestResponseForOtherSessionIsIgnored(t *testing.T) {
+	srv := newFakeServer(t)
+	srv.strayFirst = true
+	go srv.serve()
+
+	result, err := Full(testContext(t, 2*time.Second), srv.addr())
+	if err != nil {
+		t.Fatalf("Full: %v", err)
+	}
+	if len(result.Players) != 2 {
+		t.Errorf("Players = %q, want two", result.Players)
+	}
+
+	only := newFakeServer(t)
+	only.strayOnly = true
+	go only.serve()
+	_, err = Basic(testContext(t, 200*time.Millisecond), only.addr())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Basic error = %v, want the deadline to pass", err)
+	}
+}
+
+func TestParseFullStat(t *testing.T) {
+	kv := "hostname\x00Lobby\x00numplayers\x000\x00maxplayers\x0010\x00plugins\x00\x00\x00"
+	tests := []struct {
+		name    string
+		payload string
+		players []string
+		wantErr bool
+	}{
+		{"players", string(fullStatPadding) + kv + string(playerPadding) + "Steve\x00Alex\x00\x00", []string{"Steve", "Alex"}, false},
+		{"no players", string(fullStatPadding) + kv + string(playerPadding) + "\x00", []string{}, false},
+		{"missing padding", kv + string(playerPadding) + "\x00", nil, true},
+		{"missing player section", string(fullStatPadding) + kv, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseFullStat([]byte(tt.payload))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("parseFullStat succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseFullStat: %v", err)
+			}
+			if result.MOTD != "Lobby" || result.NumPlayers != 0 || result.MaxPlayers != 10 {
+				t.Errorf("key/value section parsed as %+v", result)
+			}
+			if result.Raw["plugins"] != "" || len(result.Raw) != 4 {
+				t.Errorf("Raw = %q, want 4 keys", result.Raw)
+			}
+			if !reflect.DeepEqual(result.Players, tt.players) {
+				t.Errorf("Players = %q, want %q", result.Players, tt.players)
+			}
+		})
+	}
+}
+
+func TestParsePlugins(t *testing.T) {
+	tests := []struct {
+		value   string
+		mod     string
+		plugins []string
+	}{
+		{"", "", []string{}},
+		{"Paper on Bukkit", "Paper on Bukkit", []string{}},
+		{"Paper on Bukkit: A; B", "Paper on Bukkit", []string{"A", "B"}},
+		{"Paper on Bukkit: ", "Paper on Bukkit", []string{}},
+	}
+	for _, tt := range tests {
+		mod, plugins := parsePlugins(tt.value)
+		if mod != tt.mod || !reflect.DeepEqual(plugins, tt.plugins) {
+			t.Errorf("parsePlugins(%q) = %q, %q; want %q, %q", tt.value, mod, plugins, tt.mod, tt.plugins)
+		}
+	}
+}
+
+func TestCancelInterruptsBlockedRead(t *testing.T) {
+	// A UDP socket that never answers.
+	silent, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer silent.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	_, err = Full(ctx, silent.LocalAddr().String())
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Full error = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("Full returned after %v, want it to stop on cancellation", elapsed)
+	}
+}
@@ -0,0 +1,216 @@
+// Package rcon implements a client for the Source RCON protocol used by
+// Minecraft servers when enable-rcon is set in server.properties.
+package rcon
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Packet types defined by the Source RCON protocol.
+const (
+	TypeResponseValue int32 = 0
+	TypeExecCommand   int32 = 2
+	TypeAuthResponse  int32 = 2
+	TypeAuth          int32 = 3
+)
+
+const (
+	// MaxPayload is the largest request body Minecraft accepts.
+	MaxPayload = 1446
+	// maxPacketSize bounds incoming packets; Minecraft splits responses at 4096 bytes.
+	maxPacketSize = 4096 + 10
+)
+
+// ErrAuthFailed is returned by Dial when the server rejects the password.
+var ErrAuthFailed = errors.New("rcon: authentication failed")
+
+// Packet is a single RCON packet.
+type Packet struct {
+	ID   int32
+	Type int32
+	Body string
+}
+
+// WritePacket encodes p onto w using the RCON framing: a little-endian length,
+// request ID and type followed by the null-terminated body and a padding byte.
+func WritePacket(w io.Writer, p Packet) error {
+	length := int32(4 + 4 + len(p.Body) + 2)
+	buf := make([]byte, 0, 4+length)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(length))
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(p.ID))
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(p.Type))
+	buf = append(buf, p.Body...)
+	buf = append(buf, 0, 0)
+	_, err := w.Write(buf)
+	return err
+}
+
+// ReadPacket decodes a single packet from r.
+func ReadPacket(r io.Reader) (Packet, error) {
+	var length int32
+	if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+		return Packet{}, err
+	}
+	if length < 10 || length > maxPacketSize {
+		return Packet{}, fmt.Errorf("rcon: invalid packet length %d", length)
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return Packet{}, err
+	}
+
+	body := data[8 : length-2]
+	return Packet{
+		ID:   int32(binary.LittleEndian.Uint32(data[0:4])),
+		Type: int32(binary.LittleEndian.Uint32(data[4:8])),
+		Body: string(body),
+	}, nil
+}
+
+// Client is an authenticated RCON connection. It is safe for concurrent use;
+// commands are executed one at a time.
+type Client struct {
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+	nextID int32
+
+	// Timeout bounds each command round trip. Zero means no timeout.
+	Timeout time.Duration
+}
+
+// Dial connects to addr and authenticates with password.
+func Dial(ctx context.Context, addr, password string) (*Client, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("rcon: dial failed: %w", err)
+	}
+
+	c := &Client{
+		conn:    conn,
+		reader:  bufio.NewReader(conn),
+		nextID:  1,
+		Timeout: 10 * time.Second,
+	}
+	if err := c.auth(ctx, password); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Client) auth(ctx context.Context, password string) error {
+	defer c.bind(ctx)()
+
+	id := c.newID()
+	if err := WritePacket(c.conn, Packet{ID: id, Type: TypeAuth, Body: password}); err != nil {
+		return fmt.Errorf("rcon: auth write failed: %w", err)
+	}
+
+	for {
+		p, err := ReadPacket(c.reader)
+		if err != nil {
+			return fmt.Errorf("rcon: auth read failed: %w", err)
+		}
+		// Some servers send an empty response value before the auth response.
+		if p.Type != TypeAuthResponse {
+			continue
+		}
+		if p.ID == -1 || p.ID != id {
+			return ErrAuthFailed
+		}
+		return nil
+	}
+}
+
+// Execute runs a command and returns its full response.
+func (c *Client) Execute(command string) (string, error) {
+	return c.ExecuteContext(context.Background(), command)
+}
+
+// ExecuteContext runs a command and returns its full response. Responses split
+// across several packets are reassembled by following the command with a marker
+// packet and reading until the server answers it.
+func (c *Client) ExecuteContext(ctx context.Context, command string) (string, error) {
+	if len(command) > MaxPayload {
+		return "", fmt.Errorf("rcon: command exceeds %d bytes", MaxPayload)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	defer c.bind(ctx)()
+
+	id := c.newID()
+	marker := c.newID()
+	if err := WritePacket(c.conn, Packet{ID: id, Type: TypeExecCommand, Body: command}); err != nil {
+		return "", fmt.Errorf("rcon: write failed: %w", err)
+	}
+	if err := WritePacket(c.conn, Packet{ID: marker, Type: TypeResponseValue}); err != nil {
+		return "", fmt.Errorf("rcon: write failed: %w", err)
+	}
+
+	var response strings.Builder
+	for {
+		p, err := ReadPacket(c.reader)
+		if err != nil {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return "", ctxErr
+			}
+			return "", fmt.Errorf("rcon: read failed: %w", err)
+		}
+		switch p.ID {
+		case id:
+			response.WriteString(p.Body)
+		case marker:
+			return response.String(), nil
+		}
+	}
+}
+
+// Close closes the connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Client) newID() int32 {
+	id := c.nextID
+	c.nextID++
+	if c.nextID <= 0 {
+		c.nextID = 1
+	}
+	return id
+}
+
+// bind applies the client timeout and ctx's deadline to the connection and
+// interrupts blocked I/O when ctx is cancelled. The returned function undoes it.
+func (c *Client) bind(ctx context.Context) func() {
+	deadline, ok := ctx.Deadline()
+	if c.Timeout > 0 {
+		if timeout := time.Now().Add(c.Timeout); !ok || timeout.Before(deadline) {
+			deadline, ok = timeout, true
+		}
+	}
+	if ok {
+		_ = c.conn.SetDeadline(deadline)
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		_ = c.conn.SetDeadline(time.Unix(1, 0))
+	})
+	return func() {
+		stop()
+		_ = c.conn.SetDeadline(time.Time{})
+	}
+}
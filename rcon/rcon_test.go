@@ -0,0 +1,176 @@
+package rcon
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeServer is an in-process RCON server. Responses longer than 4096 bytes
+// are split across packets like Minecraft does. After a "hang" command the
+// connection is never answered again.
+type fakeServer struct {
+	listener net.Listener
+	password string
+	handler  func(command string) string
+}
+
+func newFakeServer(t *testing.T, password string, handler func(string) string) *fakeServer {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{listener: l, password: password, handler: handler}
+	t.Cleanup(func() { _ = l.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *fakeServer) addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *fakeServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeServer) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	authed := false
+	for {
+		p, err := ReadPacket(reader)
+		if err != nil {
+			return
+		}
+		switch {
+		case p.Type == TypeAuth:
+			id := p.ID
+			if p.Body != s.password {
+				id = -1
+			} else {
+				authed = true
+			}
+			_ = WritePacket(conn, Packet{ID: p.ID, Type: TypeResponseValue})
+			_ = WritePacket(conn, Packet{ID: id, Type: TypeAuthResponse})
+		case !authed:
+			return
+		case p.Type == TypeExecCommand:
+			if p.Body == "hang" {
+				_, _ = io.Copy(io.Discard, reader)
+				return
+			}
+			response := s.handler(p.Body)
+			for {
+				chunk := response
+				if len(chunk) > 4096 {
+					chunk = chunk[:4096]
+				}
+				_ = WritePacket(conn, Packet{ID: p.ID, Type: TypeResponseValue, Body: chunk})
+				response = response[len(chunk):]
+				if response == "" {
+					break
+				}
+			}
+		default:
+			_ = WritePacket(conn, Packet{ID: p.ID, Type: TypeResponseValue, Body: "Unknown request 0"})
+		}
+	}
+}
+
+func TestDialAuthenticates(t *testing.T) {
+	s := newFakeServer(t, "secret", func(command string) string { return "ran " + command })
+
+	c, err := Dial(context.Background(), s.addr(), "secret")
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer c.Close()
+
+	got, err := c.Execute("list")
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if got != "ran list" {
+		t.Errorf("Execute = %q, want %q", got, "ran list")
+	}
+}
+
+func TestDialRejectsWrongPassword(t *testing.T) {
+	s := newFakeServer(t, "secret", func(string) string { return "" })
+
+	_, err := Dial(context.Background(), s.addr(), "wrong")
+	if !errors.Is(err, ErrAuthFailed) {
+		t.Fatalf("Dial error = %v, want ErrAuthFailed", err)
+	}
+}
+
+func TestExecuteReassemblesMultiPacketResponse(t *testing.T) {
+	long := strings.Repeat("0123456789", 1000)
+	s := newFakeServer(t, "secret", func(string) string { return long })
+
+	c, err := Dial(context.Background(), s.addr(), "secret")
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer c.Close()
+
+	for i := 0; i < 2; i++ {
+		got, err := c.Execute("help")
+		if err != nil {
+			t.Fatalf("Execute: %v", err)
+		}
+		if got != long {
+			t.Fatalf("Execute returned %d bytes, want %d", len(got), len(long))
+		}
+	}
+}
+
+func TestExecuteContextCancellation(t *testing.T) {
+	s := newFakeServer(t, "secret", func(string) string { return "" })
+
+	c, err := Dial(context.Background(), s.addr(), "secret")
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer c.Close()
+	c.Timeout = 0
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	_, err = c.ExecuteContext(ctx, "hang")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("ExecuteContext error = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("ExecuteContext returned after %v", elapsed)
+	}
+}
+
+func TestPacketRoundTrip(t *testing.T) {
+	var buf strings.Builder
+	want := Packet{ID: 42, Type: TypeExecCommand, Body: "say hi"}
+	if err := WritePacket(&buf, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadPacket(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("ReadPacket = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,193 @@
+package gomcserver
+
+import (
+	"math/rand"
+	"time"
+)
+
+// RestartMode controls when a server is automatically restarted after it exits.
+type RestartMode int
+
+const (
+	// RestartNever never restarts the server automatically.
+	RestartNever RestartMode = iota
+	// RestartOnFailure restarts the server only when it crashes.
+	RestartOnFailure
+	// RestartAlways restarts the server whenever it exits without Stop being called.
+	RestartAlways
+)
+
+// RestartPolicy configures automatic restarts and crash-loop protection.
+//
+// Zero durations fall back to the values of DefaultRestartPolicy. A MaxRestarts of
+// zero allows unlimited restarts.
+type RestartPolicy struct {
+	Mode           RestartMode
+	MaxRestarts    int           // restarts allowed within Window before giving up
+	Window         time.Duration // sliding window used to count restarts
+	InitialBackoff time.Duration // delay before the first restart in a window
+	MaxBackoff     time.Duration // upper bound for the exponential backoff
+	Jitter         float64       // random fraction (0–1) added to or removed from each delay
+}
+
+// DefaultRestartPolicy returns an on-failure policy that gives up after five crashes
+// in ten minutes.
+func DefaultRestartPolicy() *RestartPolicy {
+	return &RestartPolicy{
+		Mode:           RestartOnFailure,
+		MaxRestarts:    5,
+		Window:         10 * time.Minute,
+		InitialBackoff: time.Second,
+		MaxBackoff:     time.Minute,
+		Jitter:         0.2,
+	}
+}
+
+func (p *RestartPolicy) shouldRestart(info ExitInfo) bool {
+	if info.Requested {
+		return false
+	}
+	switch p.Mode {
+	case RestartAlways:
+		return true
+	case RestartOnFailure:
+		return info.Crashed()
+	default:
+		return false
+	}
+}
+
+func (p *RestartPolicy) window() time.Duration {
+	if p.Window <= 0 {
+		return 10 * time.Minute
+	}
+	return p.Window
+}
+
+// backoff returns the delay before the given restart attempt (starting at 1).
+func (p *RestartPolicy) backoff(attempt int) time.Duration {
+	initial, maxDelay := p.InitialBackoff, p.MaxBackoff
+	if initial <= 0 {
+		initial = time.Second
+	}
+	if maxDelay <= 0 {
+		maxDelay = time.Minute
+	}
+
+	delay := initial
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+
+	if p.Jitter > 0 {
+		jitter := p.Jitter
+		if jitter > 1 {
+			jitter = 1
+		}
+		delay += time.Duration((rand.Float64()*2 - 1) * jitter * float64(delay))
+	}
+	return delay
+}
+
+// maxLaunchFailures bounds consecutive relaunches that fail to start a process,
+// so that a server which can no longer launch at all is given up on even when
+// the policy allows unlimited restarts.
+const maxLaunchFailures = 5
+
+// maybeRestart applies the restart policy after the process exited. A restart
+// that fails to launch counts as another crash and is retried under the same
+// policy, up to maxLaunchFailures times in a row.
+func (s *Server) maybeRestart(info ExitInfo) {
+	for failures := 0; ; failures++ {
+		if failures >= maxLaunchFailures {
+			s.mu.Lock()
+			s.restarts = nil
+			s.mu.Unlock()
+			s.emit(RestartGaveUp{ExitInfo: info})
+			return
+		}
+		opts, ok := s.waitForRestart(info)
+		if !ok {
+			return
+		}
+		err := s.launchProcess(opts)
+		if err == nil {
+			return
+		}
+		s.failStart(StateCrashed)
+		info = ExitInfo{Code: -1, Time: time.Now(), Err: err}
+	}
+}
+
+// waitForRestart schedules a restart for info and sleeps until it is due. It
+// returns the options to relaunch with, or false if no restart should happen.
+func (s *Server) waitForRestart(info ExitInfo) (*StartOptions, bool) {
+	s.mu.Lock()
+	policy, opts := s.restartPolicy, s.startOpts
+	if policy == nil || opts == nil || !policy.shouldRestart(info) {
+		s.mu.Unlock()
+		return nil, false
+	}
+
+	now := time.Now()
+	cutoff := now.Add(-policy.window())
+	recent := s.restarts[:0]
+	for _, t := range s.restarts {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+	s.restarts = recent
+
+	if policy.MaxRestarts > 0 && len(s.restarts) >= policy.MaxRestarts {
+		s.restarts = nil
+		s.mu.Unlock()
+		s.emit(RestartGaveUp{ExitInfo: info})
+		return nil, false
+	}
+
+	s.restarts = append(s.restarts, now)
+	attempt := len(s.restarts)
+	delay := policy.backoff(attempt)
+	cancel := make(chan struct{})
+	s.restartCancel = cancel
+	s.mu.Unlock()
+
+	s.emit(Restarting{Attempt: attempt, Delay: delay})
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-cancel:
+		return nil, false
+	}
+
+	// Stop or Start may have claimed the pending restart while we slept.
+	s.mu.Lock()
+	if s.restartCancel != cancel {
+		s.mu.Unlock()
+		return nil, false
+	}
+	s.restartCancel = nil
+	s.mu.Unlock()
+	if !s.claimState(StateStarting) {
+		return nil, false
+	}
+	s.newRun()
+	return opts, true
+}
+
+// cancelRestart aborts a pending restart. It reports whether one was pending.
+func (s *Server) cancelRestart() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.restartCancel == nil {
+		return false
+	}
+	close(s.restartCancel)
+	s.restartCancel = nil
+	return true
+}
@@ -0,0 +1,81 @@
+package gomcserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// runtimeState is persisted under .mcserverlib/ while the server runs so that a
+// restarted controller can find and reattach to the process.
+type runtimeState struct {
+	PID          int              `json:"pid"`
+	CreateTime   int64            `json:"createTime"` // process creation time in ms since the epoch
+	StartedAt    time.Time        `json:"startedAt"`
+	Cmdline      []string         `json:"cmdline"`
+	Version      string           `json:"version"`
+	Transport    CommandTransport `json:"transport"`
+	RconAddr     string           `json:"rconAddr,omitempty"`
+	RconPassword string           `json:"rconPassword,omitempty"`
+	Detached     bool             `json:"detached"`
+	ConsoleFifo  string           `json:"consoleFifo,omitempty"`
+}
+
+func (s *Server) runtimeStatePath() string {
+	return filepath.Join(s.Directory, ".mcserverlib", "runtime.json")
+}
+
+// runtimeStateLocked captures the running process for writeRuntimeState. The
+// caller must hold s.mu.
+func (s *Server) runtimeStateLocked() runtimeState {
+	state := runtimeState{
+		PID:          s.pid,
+		StartedAt:    s.startedAt,
+		Version:      s.Version,
+		Transport:    s.transport,
+		RconAddr:     s.rconAddr,
+		RconPassword: s.rconPassword,
+		Detached:     s.detached,
+	}
+	state.Cmdline = s.cmdline
+	if s.consoleFifo != nil {
+		state.ConsoleFifo = s.consoleFifoPath()
+	}
+	return state
+}
+
+// writeRuntimeState records the running process. The file holds the RCON
+// password, so it is only readable by the owner.
+func (s *Server) writeRuntimeState(state runtimeState) error {
+	if createTime, err := processCreateTime(state.PID); err == nil {
+		state.CreateTime = createTime
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := s.runtimeStatePath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create mcserverlib directory: %w", err)
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+func (s *Server) readRuntimeState() (*runtimeState, error) {
+	data, err := os.ReadFile(s.runtimeStatePath())
+	if err != nil {
+		return nil, err
+	}
+	var state runtimeState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse runtime state: %w", err)
+	}
+	return &state, nil
+}
+
+func (s *Server) removeRuntimeState() {
+	_ = os.Remove(s.runtimeStatePath())
+}
@@ -1,6 +1,7 @@
 package gomcserver
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/magiconair/properties"
@@ -8,18 +9,21 @@ import (
 	"github.com/shirou/gopsutil/v3/process"
 	"github.com/xDefyingGravity/gomcserver/backup"
 	"github.com/xDefyingGravity/gomcserver/download"
+	"github.com/xDefyingGravity/gomcserver/java"
+	"github.com/xDefyingGravity/gomcserver/rcon"
 	"io"
 	"os"
-	"os/exec"
-	"os/signal"
 	"path/filepath"
 	"strconv"
 	"strings"
-	"syscall"
+	"sync"
 	"time"
 )
 
 // Server represents a Minecraft server instance.
+//
+// Its methods are safe for concurrent use. The exported configuration fields
+// must not be modified while the server is running.
 type Server struct {
 	Name         string
 	Version      string
@@ -29,32 +33,78 @@ type Server struct {
 	MaxMemoryMB  int
 	Props        *properties.Properties
 	EULAAccepted bool
-	PlayerCount  int
-	Players      []string
+	Software     download.Provider // server distribution, download.Vanilla if nil
+
+	// mu guards the runtime state below, Props and the legacy listeners. It is
+	// never held while events are published.
+	mu sync.Mutex
+
+	players []string
 
 	stdoutPipe io.Writer
 	stderrPipe io.Writer
 	stdinPipe  io.WriteCloser
+	writeMu    sync.Mutex
 	running    bool
-	cmd        *exec.Cmd
+	cmdline    []string
+	process    Process
 	pid        int
 
-	onStdout      func(string)
-	onStderr      func(string)
-	onPlayerJoin  func(string, int)
-	onPlayerLeave func(string, int)
+	startedAt     time.Time
+	stopRequested bool
+	sawStopping   bool
+	lockFile      *os.File
+	detached      bool
+	consoleFifo   *os.File
+	stopTail      chan struct{}
+	done          chan struct{}
+	lastExit      *ExitInfo
+
+	startOpts     *StartOptions
+	restartPolicy *RestartPolicy
+	restarts      []time.Time
+	restartCancel chan struct{}
+
+	execMu sync.Mutex
+
+	transport    CommandTransport
+	rconAddr     string
+	rconPassword string
+	rconMu       sync.Mutex
+	rconClient   *rcon.Client
 
-	signals chan os.Signal
+	watchMu    sync.Mutex
+	logWaiters []*logWaiter
+
+	stateMu sync.Mutex
+	state   ServerState
+	ready   chan struct{}
+
+	events          eventBus
+	legacyListeners map[string]func()
 }
 
 // StartOptions configures how the server is started.
 type StartOptions struct {
 	JavaPath         *string
-	JvmOptions       *[]string
+	JvmOptions       *[]string          // raw JVM flags; replace the default flags unless Jvm is set
+	Jvm              *JvmOptionsBuilder // preset and typed JVM options, combined with JvmOptions
 	StdoutPipe       io.Writer
 	StderrPipe       io.Writer
 	UseManifestCache *bool
+	ManifestMaxAge   *time.Duration // how long the cached manifest is used before revalidating it
 	CacheDir         *string
+	ShareJars        *bool                // keep server jars in CacheDir and link them into Directory
+	Downloader       *download.Downloader // HTTP client, retries and progress callback; download.DefaultDownloader if nil
+	RestartPolicy    *RestartPolicy
+	EnableRcon       *bool
+	RconPort         *int
+	RconPassword     *string
+	CommandTransport CommandTransport
+	Detached         *bool
+	Launcher         Launcher      // starts the server process, ExecLauncher by default
+	SkipDownload     *bool         // use the server.jar already in Directory
+	JavaProvider     java.Provider // downloads a runtime into CacheDir if none installed fits, nil disables
 }
 
 // ServerStats holds runtime statistics for the server process.
@@ -79,7 +129,6 @@ func NewServer(name, versionOrUrl string) *Server {
 		MaxMemoryMB: 4096,
 		Props:       properties.NewProperties(),
 		Directory:   absDir,
-		signals:     make(chan os.Signal, 1),
 	}
 }
 
@@ -90,46 +139,100 @@ func (s *Server) AcceptEULA() {
 
 // IsRunning returns true if the server is running.
 func (s *Server) IsRunning() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.running
 }
 
 // GetPID returns the process ID of the running server, or -1 if not running.
 func (s *Server) GetPID() int {
-	if s.cmd != nil && s.cmd.Process != nil {
-		return s.cmd.Process.Pid
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running && s.pid > 0 {
+		return s.pid
 	}
 	return -1
 }
 
-// SetEventListener registers a callback for a specific event type.
+// Players returns a snapshot of the names of the players currently online.
+func (s *Server) Players() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.players...)
+}
+
+// PlayerCount returns the number of players currently online.
+func (s *Server) PlayerCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.players)
+}
+
+// SetEventListener registers a callback for a specific event type, replacing any
+// callback previously registered through SetEventListener for that type.
+//
+// Deprecated: use Subscribe or SubscribeChan, which are type-checked and allow
+// multiple subscribers per event.
 func (s *Server) SetEventListener(listenerType string, fn interface{}) error {
+	var unsubscribe func()
 	switch listenerType {
 	case "stdout":
 		if f, ok := fn.(func(string)); ok {
-			s.onStdout = f
-			return nil
+			unsubscribe = Subscribe(s, func(e StdoutLine) { f(e.Line + "\n") })
 		}
 	case "stderr":
 		if f, ok := fn.(func(string)); ok {
-			s.onStderr = f
-			return nil
+			unsubscribe = Subscribe(s, func(e StderrLine) { f(e.Line + "\n") })
+		}
+	case "log":
+		if f, ok := fn.(func(LogEntry)); ok {
+			unsubscribe = Subscribe(s, func(e LogLine) { f(e.LogEntry) })
 		}
 	case "playerJoin":
 		if f, ok := fn.(func(string, int)); ok {
-			s.onPlayerJoin = f
-			return nil
+			unsubscribe = Subscribe(s, func(e PlayerJoined) { f(e.Player, e.Count) })
 		}
 	case "playerLeave":
 		if f, ok := fn.(func(string, int)); ok {
-			s.onPlayerLeave = f
-			return nil
+			unsubscribe = Subscribe(s, func(e PlayerLeft) { f(e.Player, e.Count) })
+		}
+	case "exit":
+		if f, ok := fn.(func(ExitInfo)); ok {
+			unsubscribe = Subscribe(s, func(e ServerExited) { f(e.ExitInfo) })
 		}
+	case "restarting":
+		if f, ok := fn.(func(int, time.Duration)); ok {
+			unsubscribe = Subscribe(s, func(e Restarting) { f(e.Attempt, e.Delay) })
+		}
+	case "gaveUp":
+		if f, ok := fn.(func(ExitInfo)); ok {
+			unsubscribe = Subscribe(s, func(e RestartGaveUp) { f(e.ExitInfo) })
+		}
+	case "stateChange":
+		if f, ok := fn.(func(ServerState)); ok {
+			unsubscribe = Subscribe(s, func(e StateChanged) { f(e.To) })
+		}
+	}
+	if unsubscribe == nil {
+		return fmt.Errorf("unknown or invalid listener type: %s", listenerType)
 	}
-	return fmt.Errorf("unknown or invalid listener type: %s", listenerType)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.legacyListeners == nil {
+		s.legacyListeners = make(map[string]func())
+	}
+	if previous, ok := s.legacyListeners[listenerType]; ok {
+		previous()
+	}
+	s.legacyListeners[listenerType] = unsubscribe
+	return nil
 }
 
 // SetProperty sets a server property.
 func (s *Server) SetProperty(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if s.Props == nil {
 		s.Props = properties.NewProperties()
 	}
@@ -138,90 +241,94 @@ func (s *Server) SetProperty(key, value string) {
 
 // GetProperty retrieves a server property.
 func (s *Server) GetProperty(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if s.Props == nil {
 		return "", false
 	}
 	return s.Props.Get(key)
 }
 
-// GetProperties returns all server properties.
+// GetProperties returns a copy of all server properties.
 func (s *Server) GetProperties() *properties.Properties {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	if s.Props == nil {
 		s.Props = properties.NewProperties()
 	}
-	return s.Props
+	return s.Props.FilterFunc(func(string, string) bool { return true })
 }
 
 // Start launches the Minecraft server process.
 func (s *Server) Start(opts *StartOptions) error {
+	return s.StartContext(context.Background(), opts)
+}
+
+// StartContext is like Start but aborts preparation (including the server jar
+// download) when ctx is done. Once launched, the process is not tied to ctx.
+func (s *Server) StartContext(ctx context.Context, opts *StartOptions) error {
 	if err := s.ensureDirectory(); err != nil {
 		return err
 	}
 	opts = s.applyDefaultStartOptions(opts)
-	if err := s.prepare(*opts.UseManifestCache, *opts.CacheDir); err != nil {
+	if err := s.beginStart(); err != nil {
 		return err
 	}
-	s.setupSignalHandlers(opts)
-	return s.launchProcess(opts)
-}
-
-// Stop terminates the running server process.
-func (s *Server) Stop() error {
-	if !s.running {
-		return errors.New("server is not running")
+	if err := s.claimDirectory(); err != nil {
+		s.failStart(StateStopped)
+		return err
 	}
-	if s.cmd == nil || s.cmd.Process == nil {
-		return errors.New("server process is not available")
+	if err := s.prepare(ctx, opts); err != nil {
+		s.failStart(StateStopped)
+		return err
 	}
-
-	if err := s.cmd.Process.Signal(syscall.SIGTERM); err != nil {
-		return fmt.Errorf("failed to send SIGTERM: %w", err)
+	if err := s.resolveJava(ctx, opts); err != nil {
+		s.failStart(StateStopped)
+		return err
 	}
-
-	const maxWait = 30 * time.Second
-	const interval = 1 * time.Second
-	waited := time.Duration(0)
-
-	for waited < maxWait {
-		if err := s.cmd.Process.Signal(syscall.Signal(0)); err != nil {
-			s.running = false
-			s.pid = -1
-			return nil
-		}
-		time.Sleep(interval)
-		waited += interval
+	if err := ctx.Err(); err != nil {
+		s.failStart(StateStopped)
+		return err
 	}
-
-	if err := s.cmd.Process.Kill(); err != nil {
-		return fmt.Errorf("failed to force kill server after timeout: %w", err)
+	s.mu.Lock()
+	s.startOpts = opts
+	s.restartPolicy = opts.RestartPolicy
+	s.restarts = nil
+	s.mu.Unlock()
+	if err := s.launchProcess(opts); err != nil {
+		s.failStart(StateStopped)
+		return err
 	}
-
-	s.running = false
-	s.pid = -1
 	return nil
 }
 
-// SendCommand sends a command to the server's stdin.
+// Stop gracefully shuts the server down using the default StopOptions.
+func (s *Server) Stop() error {
+	return s.StopWithOptions(nil)
+}
+
+// SendCommand sends a command to the server over the configured transport
+// (stdin by default, or RCON).
 func (s *Server) SendCommand(command string) error {
-	if !s.running {
+	if !s.IsRunning() {
 		return errors.New("server is not running")
 	}
-	if s.stdinPipe == nil {
-		return errors.New("stdin pipe is not available")
-	}
-	_, err := io.WriteString(s.stdinPipe, command+"\n")
-	return err
+	return s.writeCommand(command)
 }
 
 // GetStats returns runtime statistics for the server process.
 func (s *Server) GetStats() (*ServerStats, error) {
-	if !s.running {
+	s.mu.Lock()
+	running, pid := s.running, s.pid
+	s.mu.Unlock()
+
+	if !running {
 		return nil, errors.New("server is not running")
 	}
-	if s.pid <= 0 {
+	if pid <= 0 {
 		return nil, errors.New("server process PID is not available")
 	}
-	return getPIDStats(int32(s.pid))
+	return getPIDStats(int32(pid))
 }
 
 // SetDifficulty sets the server difficulty.
@@ -283,92 +390,227 @@ func (s *Server) applyDefaultStartOptions(opts *StartOptions) *StartOptions {
 		defaultUseManifestCache := true
 		opts.UseManifestCache = &defaultUseManifestCache
 	}
+	if opts.ManifestMaxAge == nil {
+		defaultManifestMaxAge := download.DefaultManifestMaxAge
+		opts.ManifestMaxAge = &defaultManifestMaxAge
+	}
+	if opts.ShareJars == nil {
+		defaultShareJars := true
+		opts.ShareJars = &defaultShareJars
+	}
+	if opts.EnableRcon == nil {
+		defaultEnableRcon := false
+		opts.EnableRcon = &defaultEnableRcon
+	}
+	if opts.Detached == nil {
+		defaultDetached := false
+		opts.Detached = &defaultDetached
+	}
+	if opts.Launcher == nil {
+		opts.Launcher = ExecLauncher{}
+	}
+	if opts.SkipDownload == nil {
+		defaultSkipDownload := false
+		opts.SkipDownload = &defaultSkipDownload
+	}
 	return opts
 }
 
-func (s *Server) setupSignalHandlers(opts *StartOptions) {
-	signal.Notify(s.signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
-	go func() {
-		for sig := range s.signals {
-			switch sig {
-			case syscall.SIGINT, syscall.SIGTERM:
-				_ = s.Stop()
-			case syscall.SIGHUP:
-				_ = s.Stop()
-				_ = s.Start(opts)
-			}
-		}
-	}()
-}
-
 func (s *Server) launchProcess(opts *StartOptions) error {
-	if opts.JavaPath == nil {
-		javaPath, err := exec.LookPath("java")
-		if err != nil {
-			return fmt.Errorf("java executable not found: %w", err)
-		}
-		opts.JavaPath = &javaPath
+
+	args, err := s.jvmArgs(opts)
+	if err != nil {
+		return err
 	}
+	args = append(args, s.launchArgs()...)
+	args = append(args, "nogui")
 
-	if opts.JvmOptions == nil {
-		defaultJvmOptions := []string{
-			"-XX:+UseG1GC",
-			"-XX:+UnlockExperimentalVMOptions",
-		}
-		opts.JvmOptions = &defaultJvmOptions
+	spec := ProcessSpec{
+		Path:     *opts.JavaPath,
+		Args:     args,
+		Dir:      s.Directory,
+		Detached: *opts.Detached,
 	}
 
-	args := append(
-		*opts.JvmOptions,
-		"-Xms"+strconv.Itoa(s.MinMemoryMB)+"M",
-		"-Xmx"+strconv.Itoa(s.MaxMemoryMB)+"M",
-		"-jar", "server.jar", "nogui",
-	)
+	if err := s.acquireLock(); err != nil {
+		return err
+	}
 
-	s.cmd = exec.Command(*opts.JavaPath, args...)
-	s.cmd.Dir = s.Directory
+	s.mu.Lock()
+	transport := s.transport
+	s.mu.Unlock()
 
-	stdout, err := s.cmd.StdoutPipe()
-	if err != nil {
-		return fmt.Errorf("failed to get stdout pipe: %w", err)
+	var fifo *os.File
+	var startTail func()
+	if spec.Detached {
+		fifo, startTail, err = s.setupDetachedIO(&spec, transport)
+		if err != nil {
+			return err
+		}
 	}
-	go s.listenToStdout(stdout)
 
-	stderr, err := s.cmd.StderrPipe()
-	if err != nil {
-		return fmt.Errorf("failed to get stderr pipe: %w", err)
-	}
-	go s.listenToStderr(stderr)
+	s.setState(StateStarting)
 
-	stdinPipe, err := s.cmd.StdinPipe()
-	if err != nil {
-		return fmt.Errorf("failed to get stdin pipe: %w", err)
+	proc, err := opts.Launcher.Launch(spec)
+	// The child's end of the console FIFO is only needed by the process.
+	if closer, ok := spec.Stdin.(io.Closer); ok {
+		_ = closer.Close()
 	}
-	s.stdinPipe = stdinPipe
-
-	if err := s.cmd.Start(); err != nil {
+	if err != nil {
+		if fifo != nil {
+			_ = fifo.Close()
+		}
 		return err
 	}
 
+	stdin := proc.Stdin()
+	if fifo != nil {
+		stdin = fifo
+	}
+	s.mu.Lock()
+	done := s.done
+	s.cmdline = append([]string{spec.Path}, spec.Args...)
+	s.stdinPipe = stdin
+	s.consoleFifo = fifo
+	s.detached = spec.Detached
 	s.running = true
-	s.pid = s.cmd.Process.Pid
+	s.process = proc
+	s.pid = proc.Pid()
+	s.startedAt = time.Now()
+	s.stopRequested = false
+	s.sawStopping = false
+	state := s.runtimeStateLocked()
 	s.stdoutPipe = nil
 	s.stderrPipe = nil
+	s.mu.Unlock()
+
+	var readers sync.WaitGroup
+	if startTail != nil {
+		startTail()
+	} else {
+		s.startReaders(proc, &readers)
+	}
+	if state.PID > 0 {
+		_ = s.writeRuntimeState(state)
+	}
+	go s.supervise(proc, &readers, done)
 	return nil
 }
 
-func (s *Server) prepare(useManifestCache bool, cacheDir string) error {
+// startReaders follows the output pipes of a process until they are closed.
+func (s *Server) startReaders(proc Process, readers *sync.WaitGroup) {
+	if stdout := proc.Stdout(); stdout != nil {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			s.listenToStdout(stdout)
+		}()
+	}
+	if stderr := proc.Stderr(); stderr != nil {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			s.listenToStderr(stderr)
+		}()
+	}
+}
+
+func (s *Server) prepare(ctx context.Context, opts *StartOptions) error {
 	if err := s.validateConfig(); err != nil {
+		s.setState(StateStopped)
 		return err
 	}
+	transport := opts.CommandTransport
+	if *opts.Detached && !consoleFifoSupported {
+		transport = TransportRcon
+	}
+	s.mu.Lock()
+	s.transport = transport
+	s.rconAddr = ""
+	s.mu.Unlock()
+	if *opts.EnableRcon || transport == TransportRcon {
+		if err := s.configureRcon(opts); err != nil {
+			s.setState(StateStopped)
+			return err
+		}
+	}
+	// The server only listens on s.Port if server.properties says so.
+	s.SetProperty("server-port", strconv.Itoa(s.Port))
 	if err := s.writeEULA(); err != nil {
+		s.setState(StateStopped)
 		return err
 	}
 	if err := s.writeProperties(); err != nil {
+		s.setState(StateStopped)
+		return err
+	}
+	if *opts.SkipDownload {
+		return nil
+	}
+	s.setState(StateDownloading)
+	if err := s.install(ctx, opts); err != nil {
+		s.setState(StateStopped)
 		return err
 	}
-	_, err := download.DownloadServerJar(s.Version, s.Directory, useManifestCache, cacheDir)
-	return err
+	return nil
+}
+
+// install downloads the server distribution and records how to launch it.
+func (s *Server) install(ctx context.Context, opts *StartOptions) error {
+	provider := s.Software
+	if provider == nil {
+		provider = &download.Vanilla{}
+	}
+	var jars *download.JarCache
+	if *opts.ShareJars {
+		jars = &download.JarCache{Dir: *opts.CacheDir}
+	}
+	inst, err := provider.Install(ctx, download.InstallRequest{
+		Version:        s.Version,
+		Directory:      s.Directory,
+		UseCache:       *opts.UseManifestCache,
+		CacheDir:       *opts.CacheDir,
+		ManifestMaxAge: *opts.ManifestMaxAge,
+		Jars:           jars,
+		Downloader:     s.downloader(opts),
+		Java: func(required int) (string, error) {
+			return s.findJava(ctx, opts, required)
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to install %s %s: %w", provider.Name(), s.Version, err)
+	}
+	if inst.JavaVersion == 0 {
+		inst.JavaVersion = download.JavaVersionFor(inst.Version)
+	}
+	return download.WriteInstallation(s.Directory, inst)
+}
+
+// downloader returns StartOptions.Downloader, or download.DefaultDownloader,
+// with a progress callback that also publishes DownloadProgress events.
+func (s *Server) downloader(opts *StartOptions) *download.Downloader {
+	base := opts.Downloader
+	if base == nil {
+		base = download.DefaultDownloader
+	}
+	downloader := *base
+	downloader.Progress = func(p download.Progress) {
+		if base.Progress != nil {
+			base.Progress(p)
+		}
+		s.emit(DownloadProgress{p})
+	}
+	return &downloader
+}
+
+// launchArgs returns the arguments that start the installed server, falling
+// back to server.jar when nothing was recorded (for example with
+// SkipDownload).
+func (s *Server) launchArgs() []string {
+	if inst, err := download.ReadInstallation(s.Directory); err == nil {
+		return inst.LaunchArgs()
+	}
+	return []string{"-jar", "server.jar"}
 }
 
 func (s *Server) validateConfig() error {
@@ -384,10 +626,6 @@ func (s *Server) validateConfig() error {
 		return fmt.Errorf("port %d is out of range (1–65535)", s.Port)
 	}
 
-	if s.running {
-		return errors.New("server is already running")
-	}
-
 	if !s.EULAAccepted {
 		return errors.New("EULA not accepted")
 	}
@@ -435,7 +673,13 @@ func (s *Server) writeEULA() error {
 }
 
 func (s *Server) writeProperties() error {
-	if s.Props == nil {
+	s.mu.Lock()
+	var props *properties.Properties
+	if s.Props != nil {
+		props = s.Props.FilterFunc(func(string, string) bool { return true })
+	}
+	s.mu.Unlock()
+	if props == nil {
 		return nil
 	}
 
@@ -449,8 +693,8 @@ func (s *Server) writeProperties() error {
 		}
 	}
 
-	for _, key := range s.Props.Keys() {
-		val, _ := s.Props.Get(key)
+	for _, key := range props.Keys() {
+		val, _ := props.Get(key)
 		err := existingProps.SetValue(key, val)
 		if err != nil {
 			return err
@@ -461,62 +705,59 @@ func (s *Server) writeProperties() error {
 }
 
 func (s *Server) listenToStdout(r io.Reader) {
-	buf := make([]byte, 4096)
-	for {
-		n, err := r.Read(buf)
-		if n > 0 && s.onStdout != nil {
-			s.internalOnStdout(string(buf[:n]))
-			s.onStdout(string(buf[:n]))
-		}
-		if err != nil {
-			break
-		}
-	}
+	readLines(r, s.handleStdoutLine)
+}
+
+func (s *Server) handleStdoutLine(line string) {
+	entry := ParseLogLine(line)
+	s.internalOnStdout(entry)
+	s.emit(StdoutLine{Line: line})
+	s.emit(LogLine{LogEntry: entry})
 }
 
 func (s *Server) listenToStderr(r io.Reader) {
-	buf := make([]byte, 4096)
-	for {
-		n, err := r.Read(buf)
-		if n > 0 && s.onStderr != nil {
-			s.internalOnStderr(string(buf[:n]))
-			s.onStderr(string(buf[:n]))
-		}
-		if err != nil {
-			break
-		}
-	}
+	readLines(r, func(line string) {
+		s.internalOnStderr(line)
+		s.emit(StderrLine{Line: line})
+	})
 }
 
-func (s *Server) internalOnStdout(message string) {
-	if strings.Contains(message, "joined the game") || strings.Contains(message, "left the game") {
-		parts := strings.SplitN(message, "]: ", 2)
-		if len(parts) < 2 {
-			return
-		}
-		line := parts[1]
+func (s *Server) internalOnStdout(entry LogEntry) {
+	s.notifyLogWaiters(entry.Raw)
+	s.detectReady(entry.Message)
+
+	line := entry.Message
+	if stoppingPattern.MatchString(line) {
+		s.mu.Lock()
+		s.sawStopping = true
+		s.mu.Unlock()
+	}
+
+	if ev := parseGameMessage(line, s.Players()); ev != nil {
+		s.emit(ev)
+		return
+	}
+	if strings.Contains(line, "joined the game") || strings.Contains(line, "left the game") {
 		words := strings.Split(line, " ")
 		if len(words) >= 1 {
 			playerName := words[0]
 			if strings.Contains(line, "joined the game") {
-				s.PlayerCount++
-				if s.onPlayerJoin != nil {
-					s.onPlayerJoin(playerName, s.PlayerCount)
-				}
-				s.Players = append(s.Players, playerName)
+				s.mu.Lock()
+				s.players = append(s.players, playerName)
+				count := len(s.players)
+				s.mu.Unlock()
+				s.emit(PlayerJoined{Player: playerName, Count: count})
 			} else if strings.Contains(line, "left the game") {
-				if s.PlayerCount > 0 {
-					s.PlayerCount--
-				}
-				if s.onPlayerLeave != nil {
-					s.onPlayerLeave(playerName, s.PlayerCount)
-				}
-				for i, player := range s.Players {
+				s.mu.Lock()
+				for i, player := range s.players {
 					if player == playerName {
-						s.Players = append(s.Players[:i], s.Players[i+1:]...)
+						s.players = append(s.players[:i], s.players[i+1:]...)
 						break
 					}
 				}
+				count := len(s.players)
+				s.mu.Unlock()
+				s.emit(PlayerLeft{Player: playerName, Count: count})
 			}
 		}
 	}
@@ -567,13 +808,20 @@ func getTotalMemoryMB() (int, error) {
 }
 
 func (s *Server) Backup(nonBlocking bool) error {
+	return s.BackupContext(context.Background(), nonBlocking)
+}
+
+// BackupContext is like Backup but aborts the archive when ctx is done.
+func (s *Server) BackupContext(ctx context.Context, nonBlocking bool) error {
 	backupDir := filepath.Join(s.Directory, "backups")
 	if err := os.MkdirAll(backupDir, 0755); err != nil {
 		return fmt.Errorf("failed to create backup directory: %w", err)
 	}
 
 	doBackup := func() error {
-		return backup.CreateBackup(s.Directory, backupDir)
+		path, err := backup.CreateBackupContext(ctx, s.Directory, backupDir)
+		s.emit(BackupCompleted{Path: path, Err: err})
+		return err
 	}
 
 	if nonBlocking {
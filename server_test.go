@@ -0,0 +1,362 @@
+package gomcserver_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/xDefyingGravity/gomcserver"
+	"github.com/xDefyingGravity/gomcserver/download"
+	"github.com/xDefyingGravity/gomcserver/gomcservertest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// startFake starts a server backed by a gomcservertest fake and waits for it to
+// become ready. The server is stopped when the test ends.
+func startFake(t *testing.T) (*gomcserver.Server, *gomcservertest.Fake) {
+	t.Helper()
+	srv, fake := gomcservertest.NewServer(t.TempDir())
+	if err := srv.Start(fake.StartOptions()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() {
+		if srv.IsRunning() {
+			_ = srv.Stop()
+		}
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.WaitUntilReady(ctx); err != nil {
+		t.Fatalf("WaitUntilReady: %v", err)
+	}
+	return srv, fake
+}
+
+func TestConcurrentAccessors(t *testing.T) {
+	srv, fake := startFake(t)
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				_ = srv.Players()
+				_ = srv.PlayerCount()
+				_ = srv.State()
+				_ = srv.IsRunning()
+				_ = srv.IsReady()
+				_ = srv.GetPID()
+			}
+		}()
+	}
+
+	joined, unsubscribe := gomcserver.SubscribeChan[gomcserver.PlayerJoined](srv, 16)
+	defer unsubscribe()
+	for i := 0; i < 5; i++ {
+		fake.Join(fmt.Sprintf("Player%d", i))
+	}
+	for i := 0; i < 5; i++ {
+		select {
+		case <-joined:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for join %d", i)
+		}
+	}
+	if got := srv.PlayerCount(); got != 5 {
+		t.Errorf("PlayerCount = %d, want 5", got)
+	}
+	if got := len(srv.Players()); got != 5 {
+		t.Errorf("len(Players) = %d, want 5", got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var cmds sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		cmds.Add(1)
+		go func(i int) {
+			defer cmds.Done()
+			result, err := srv.ExecCommand(ctx, fmt.Sprintf("say hello %d", i))
+			if err != nil {
+				t.Errorf("ExecCommand: %v", err)
+				return
+			}
+			if want := fmt.Sprintf("[Server] hello %d", i); result.Output() != want {
+				t.Errorf("ExecCommand output = %q, want %q", result.Output(), want)
+			}
+		}(i)
+	}
+	cmds.Wait()
+
+	if err := srv.Stop(); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	close(stop)
+	wg.Wait()
+
+	if srv.IsRunning() {
+		t.Error("server still running after Stop")
+	}
+	if got := srv.State(); got != gomcserver.StateStopped {
+		t.Errorf("State = %v, want %v", got, gomcserver.StateStopped)
+	}
+	if got := srv.PlayerCount(); got != 0 {
+		t.Errorf("PlayerCount after Stop = %d, want 0", got)
+	}
+}
+
+// next waits for the next value on ch.
+func next[E any](t *testing.T, ch <-chan E) E {
+	t.Helper()
+	select {
+	case e := <-ch:
+		return e
+	case <-time.After(5 * time.Second):
+		var zero E
+		t.Fatalf("timed out waiting for %T", zero)
+		return zero
+	}
+}
+
+func TestFakeEvents(t *testing.T) {
+	srv, fake := startFake(t)
+
+	joined, unsubJoin := gomcserver.SubscribeChan[gomcserver.PlayerJoined](srv, 4)
+	defer unsubJoin()
+	chats, unsubChat := gomcserver.SubscribeChan[gomcserver.ChatMessage](srv, 4)
+	defer unsubChat()
+	left, unsubLeave := gomcserver.SubscribeChan[gomcserver.PlayerLeft](srv, 4)
+	defer unsubLeave()
+
+	fake.Join("Steve")
+	if e := next(t, joined); e.Player != "Steve" || e.Count != 1 {
+		t.Errorf("PlayerJoined = %+v, want Steve with count 1", e)
+	}
+	fake.Chat("Steve", "hello")
+	if e := next(t, chats); e.Player != "Steve" || e.Message != "hello" {
+		t.Errorf("ChatMessage = %+v, want <Steve> hello", e)
+	}
+	fake.Leave("Steve")
+	if e := next(t, left); e.Player != "Steve" || e.Count != 0 {
+		t.Errorf("PlayerLeft = %+v, want Steve with count 0", e)
+	}
+}
+
+func TestFakeCommands(t *testing.T) {
+	srv, fake := startFake(t)
+	fake.Handle("seed", func(string) []string { return []string{"Seed: [42]"} })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, err := srv.ExecCommand(ctx, "seed")
+	if err != nil {
+		t.Fatalf("ExecCommand: %v", err)
+	}
+	if result.Output() != "Seed: [42]" {
+		t.Errorf("ExecCommand output = %q, want %q", result.Output(), "Seed: [42]")
+	}
+
+	fake.Join("Alex")
+	list, err := srv.ListPlayers(ctx)
+	if err != nil {
+		t.Fatalf("ListPlayers: %v", err)
+	}
+	if list.Online != 1 || list.Max != 20 || len(list.Players) != 1 || list.Players[0] != "Alex" {
+		t.Errorf("ListPlayers = %+v, want Alex of 20", list)
+	}
+}
+
+func TestFakeCrash(t *testing.T) {
+	srv, fake := startFake(t)
+	exited, unsubscribe := gomcserver.SubscribeChan[gomcserver.ServerExited](srv, 1)
+	defer unsubscribe()
+
+	fake.Crash("boom")
+	e := next(t, exited)
+	if !e.Crashed() || e.Code != 1 {
+		t.Errorf("ServerExited = %+v, want a crash with code 1", e.ExitInfo)
+	}
+	if got := srv.State(); got != gomcserver.StateCrashed {
+		t.Errorf("State = %v, want %v", got, gomcserver.StateCrashed)
+	}
+	if info, ok := srv.LastExit(); !ok || info.Code != 1 {
+		t.Errorf("LastExit = %+v, %v; want code 1", info, ok)
+	}
+}
+
+// failingLauncher launches through a Fake until fail is set, after which every
+// launch fails.
+type failingLauncher struct {
+	*gomcservertest.Fake
+	fail     atomic.Bool
+	attempts atomic.Int32
+}
+
+func (l *failingLauncher) Launch(spec gomcserver.ProcessSpec) (gomcserver.Process, error) {
+	if l.fail.Load() {
+		l.attempts.Add(1)
+		return nil, errors.New("launch failed")
+	}
+	return l.Fake.Launch(spec)
+}
+
+// crashWithFailingRelaunch starts a server under policy, makes every further
+// launch fail and crashes it, returning once the policy gives up.
+func crashWithFailingRelaunch(t *testing.T, dir string, policy *gomcserver.RestartPolicy) (*gomcserver.Server, *failingLauncher) {
+	t.Helper()
+	srv, fake := gomcservertest.NewServer(dir)
+	launcher := &failingLauncher{Fake: fake}
+	opts := fake.StartOptions()
+	opts.Launcher = launcher
+	opts.RestartPolicy = policy
+	if err := srv.Start(opts); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.WaitUntilReady(ctx); err != nil {
+		t.Fatalf("WaitUntilReady: %v", err)
+	}
+
+	gaveUp, unsubscribe := gomcserver.SubscribeChan[gomcserver.RestartGaveUp](srv, 1)
+	defer unsubscribe()
+	launcher.fail.Store(true)
+	fake.Crash("boom")
+	next(t, gaveUp)
+	return srv, launcher
+}
+
+func TestRestartGaveUpReleasesLock(t *testing.T) {
+	dir := t.TempDir()
+	srv, launcher := crashWithFailingRelaunch(t, dir, &gomcserver.RestartPolicy{
+		Mode:           gomcserver.RestartOnFailure,
+		MaxRestarts:    2,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+	})
+	if n := launcher.attempts.Load(); n != 2 {
+		t.Errorf("relaunch attempts = %d, want 2", n)
+	}
+	if got := srv.State(); got != gomcserver.StateCrashed {
+		t.Errorf("State = %v, want %v", got, gomcserver.StateCrashed)
+	}
+
+	// Another controller can take over the directory.
+	other, fake := gomcservertest.NewServer(dir)
+	if err := other.Start(fake.StartOptions()); err != nil {
+		t.Fatalf("Start by another controller: %v", err)
+	}
+	if err := other.Stop(); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+}
+
+func TestRestartUnlimitedStopsOnLaunchFailures(t *testing.T) {
+	dir := t.TempDir()
+	_, launcher := crashWithFailingRelaunch(t, dir, &gomcserver.RestartPolicy{
+		Mode:           gomcserver.RestartOnFailure,
+		MaxRestarts:    0,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     time.Millisecond,
+	})
+	n := launcher.attempts.Load()
+	if n == 0 || n > 10 {
+		t.Errorf("relaunch attempts = %d, want a small bounded number", n)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if after := launcher.attempts.Load(); after != n {
+		t.Errorf("relaunches continued after RestartGaveUp: %d, then %d", n, after)
+	}
+}
+
+// blockingProvider installs nothing, holding each install until release is
+// closed, or failing it with err.
+type blockingProvider struct {
+	entered chan struct{}
+	release chan struct{}
+	err     error
+}
+
+func (p *blockingProvider) Name() string { return "blocking" }
+
+func (p *blockingProvider) Install(ctx context.Context, req download.InstallRequest) (*download.Installation, error) {
+	p.entered <- struct{}{}
+	<-p.release
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &download.Installation{Software: p.Name(), Jar: "server.jar"}, nil
+}
+
+// startBlocked starts srv in the background with an install held by provider
+// and returns once the install has begun, along with StartContext's result.
+func startBlocked(t *testing.T, srv *gomcserver.Server, fake *gomcservertest.Fake, provider *blockingProvider) <-chan error {
+	t.Helper()
+	srv.Software = provider
+	opts := fake.StartOptions()
+	skipDownload := false
+	opts.SkipDownload = &skipDownload
+	started := make(chan error, 1)
+	go func() { started <- srv.Start(opts) }()
+	next(t, provider.entered)
+	return started
+}
+
+// waitReady runs WaitUntilReady in the background.
+func waitReady(srv *gomcserver.Server) <-chan error {
+	result := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		result <- srv.WaitUntilReady(ctx)
+	}()
+	return result
+}
+
+func TestWaitUntilReadyDuringPreparation(t *testing.T) {
+	srv, fake := gomcservertest.NewServer(t.TempDir())
+	provider := &blockingProvider{entered: make(chan struct{}), release: make(chan struct{})}
+
+	for run := 1; run <= 2; run++ {
+		started := startBlocked(t, srv, fake, provider)
+		ready := waitReady(srv)
+		time.Sleep(20 * time.Millisecond) // let WaitUntilReady block on the install
+		provider.release <- struct{}{}
+
+		if err := next(t, started); err != nil {
+			t.Fatalf("run %d: Start: %v", run, err)
+		}
+		if err := next(t, ready); err != nil {
+			t.Fatalf("run %d: WaitUntilReady: %v", run, err)
+		}
+		if err := srv.Stop(); err != nil {
+			t.Fatalf("run %d: Stop: %v", run, err)
+		}
+	}
+}
+
+func TestWaitUntilReadyFailedStart(t *testing.T) {
+	srv, fake := gomcservertest.NewServer(t.TempDir())
+	provider := &blockingProvider{entered: make(chan struct{}), release: make(chan struct{}), err: errors.New("no such version")}
+
+	started := startBlocked(t, srv, fake, provider)
+	ready := waitReady(srv)
+	close(provider.release)
+
+	if err := next(t, started); err == nil {
+		t.Fatal("Start succeeded with a failing install")
+	}
+	if err := next(t, ready); err == nil {
+		t.Fatal("WaitUntilReady succeeded for a failed start")
+	}
+}
@@ -0,0 +1,89 @@
+package gomcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// HandleSignals stops the given servers when the process receives SIGINT or
+// SIGTERM, and restarts them on SIGHUP. Signal handling is opt-in: the library
+// never installs handlers on its own, so applications that own their signals
+// can call StopContext themselves instead.
+//
+// HandleSignals blocks until ctx is done, returning nil, or until a terminating
+// signal has been handled, returning the errors of servers that failed to
+// stop. All servers are stopped (or restarted) in parallel. The handlers are
+// removed with signal.Stop before it returns.
+func HandleSignals(ctx context.Context, servers ...*Server) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case sig := <-signals:
+			if sig == syscall.SIGHUP {
+				_ = forEachServer(servers, func(s *Server) error {
+					return s.restart(ctx)
+				})
+				continue
+			}
+			// Shutdown is not tied to ctx, which applications commonly cancel on
+			// the same signal.
+			return forEachServer(servers, func(s *Server) error {
+				return s.stopForSignal(context.Background())
+			})
+		}
+	}
+}
+
+// forEachServer runs fn for every server concurrently and joins the errors.
+func forEachServer(servers []*Server, fn func(*Server) error) error {
+	errs := make([]error, len(servers))
+	var wg sync.WaitGroup
+	for i, s := range servers {
+		wg.Add(1)
+		go func(i int, s *Server) {
+			defer wg.Done()
+			if err := fn(s); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", s.Name, err)
+			}
+		}(i, s)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// stopForSignal stops the server if it is running and cancels any pending
+// automatic restart. Servers that are not running are left alone.
+func (s *Server) stopForSignal(ctx context.Context) error {
+	if !s.IsRunning() {
+		if s.cancelRestart() {
+			s.setState(StateStopped)
+		}
+		return nil
+	}
+	return s.StopContext(ctx, nil)
+}
+
+// restart stops a running server and starts it again with the options of its
+// last Start call.
+func (s *Server) restart(ctx context.Context) error {
+	s.mu.Lock()
+	opts := s.startOpts
+	s.mu.Unlock()
+	if opts == nil || !s.IsRunning() {
+		return nil
+	}
+	if err := s.StopContext(context.Background(), nil); err != nil {
+		return err
+	}
+	return s.StartContext(ctx, opts)
+}
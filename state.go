@@ -0,0 +1,193 @@
+package gomcserver
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServerState is a stage in the server lifecycle.
+type ServerState int
+
+const (
+	StateStopped ServerState = iota
+	StatePreparing
+	StateDownloading
+	StateStarting
+	StateReady
+	StateStopping
+	StateCrashed
+)
+
+// String returns the lowercase name of the state.
+func (st ServerState) String() string {
+	switch st {
+	case StateStopped:
+		return "stopped"
+	case StatePreparing:
+		return "preparing"
+	case StateDownloading:
+		return "downloading"
+	case StateStarting:
+		return "starting"
+	case StateReady:
+		return "ready"
+	case StateStopping:
+		return "stopping"
+	case StateCrashed:
+		return "crashed"
+	default:
+		return "unknown"
+	}
+}
+
+// doneLoadingPattern matches the line vanilla and its forks print once the world
+// has loaded, e.g. `Done (3.142s)! For help, type "help"`.
+var doneLoadingPattern = regexp.MustCompile(`Done \(([0-9.,]+)(m?s)\)! For help, type "help"`)
+
+// State returns the current lifecycle state of the server.
+func (s *Server) State() ServerState {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	return s.state
+}
+
+// IsReady returns true once the server has finished loading and accepts players.
+func (s *Server) IsReady() bool {
+	return s.State() == StateReady
+}
+
+// WaitUntilReady blocks until the server has finished loading. It can be called
+// as soon as Start has begun, including while the server is still being
+// prepared or downloaded. It returns an error if the start fails, the process
+// exits first or ctx is done.
+func (s *Server) WaitUntilReady(ctx context.Context) error {
+	s.mu.Lock()
+	done := s.done
+	s.stateMu.Lock()
+	state, ready := s.state, s.ready
+	s.stateMu.Unlock()
+	s.mu.Unlock()
+
+	if state == StateReady {
+		return nil
+	}
+	if ready == nil || done == nil {
+		return errors.New("server has not been started")
+	}
+
+	select {
+	case <-ready:
+		return nil
+	case <-done:
+		return errors.New("server exited before becoming ready")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// setState moves the server to a new state and publishes a StateChanged event.
+func (s *Server) setState(state ServerState) {
+	s.stateMu.Lock()
+	from := s.state
+	if from == state {
+		s.stateMu.Unlock()
+		return
+	}
+	s.state = state
+	if state == StateReady && s.ready != nil {
+		select {
+		case <-s.ready:
+		default:
+			close(s.ready)
+		}
+	}
+	s.stateMu.Unlock()
+
+	s.emit(StateChanged{From: from, To: state})
+}
+
+// claimState moves the server from Stopped or Crashed to the given state. It
+// reports false if the server is already being started or is running, so that
+// concurrent Start, Attach and restart attempts cannot launch twice.
+func (s *Server) claimState(state ServerState) bool {
+	s.stateMu.Lock()
+	from := s.state
+	if from != StateStopped && from != StateCrashed {
+		s.stateMu.Unlock()
+		return false
+	}
+	s.state = state
+	s.stateMu.Unlock()
+
+	s.emit(StateChanged{From: from, To: state})
+	return true
+}
+
+// beginStart claims the server for a Start or Attach call, cancelling any
+// pending automatic restart.
+func (s *Server) beginStart() error {
+	s.cancelRestart()
+	if !s.claimState(StatePreparing) {
+		return errors.New("server is already running")
+	}
+	s.newRun()
+	return nil
+}
+
+// newRun creates the channels of a new run before anything is launched, so
+// that WaitUntilReady and Wait follow the run from the moment it is claimed.
+// mu is taken before stateMu so that both are replaced together.
+func (s *Server) newRun() {
+	s.mu.Lock()
+	s.stateMu.Lock()
+	s.ready = make(chan struct{})
+	s.done = make(chan struct{})
+	s.stateMu.Unlock()
+	s.mu.Unlock()
+}
+
+// failStart ends a run that could not be launched: it releases the directory
+// lock, moves the server to state and wakes up anyone waiting on the run.
+func (s *Server) failStart(state ServerState) {
+	s.releaseLock()
+	s.setState(state)
+	s.mu.Lock()
+	done := s.done
+	s.mu.Unlock()
+	if done != nil {
+		select {
+		case <-done:
+		default:
+			close(done)
+		}
+	}
+}
+
+// detectReady marks the server ready when it prints its startup completion line.
+func (s *Server) detectReady(message string) {
+	if s.State() != StateStarting {
+		return
+	}
+	m := doneLoadingPattern.FindStringSubmatch(message)
+	if m == nil {
+		return
+	}
+	s.setState(StateReady)
+	s.emit(ServerReady{LoadTime: parseLoadTime(m[1], m[2])})
+}
+
+// parseLoadTime converts the duration printed in the startup completion line.
+func parseLoadTime(value, unit string) time.Duration {
+	f, err := strconv.ParseFloat(strings.ReplaceAll(value, ",", "."), 64)
+	if err != nil {
+		return 0
+	}
+	if unit == "ms" {
+		return time.Duration(f * float64(time.Millisecond))
+	}
+	return time.Duration(f * float64(time.Second))
+}
@@ -0,0 +1,50 @@
+package gomcserver
+
+import (
+	"context"
+	"github.com/xDefyingGravity/gomcserver/ping"
+	"github.com/xDefyingGravity/gomcserver/query"
+	"net"
+	"strconv"
+)
+
+// Ping queries the server's public status over the Server List Ping protocol on
+// s.Port, the same way the Minecraft client's server list does.
+func (s *Server) Ping(ctx context.Context) (*ping.Status, error) {
+	return ping.Ping(ctx, net.JoinHostPort("127.0.0.1", strconv.Itoa(s.Port)))
+}
+
+// EnableQuery turns on the GameSpy4 query protocol on the given UDP port. Like
+// other properties, it takes effect the next time the server starts.
+func (s *Server) EnableQuery(port int) {
+	s.SetProperty("enable-query", "true")
+	s.SetProperty("query.port", strconv.Itoa(port))
+}
+
+// Query runs a full stat query against the server. The port is taken from the
+// query.port property, falling back to the server-port property (which Start
+// sets to s.Port) as the server itself does.
+func (s *Server) Query(ctx context.Context) (*query.QueryResult, error) {
+	port := s.Port
+	for _, key := range []string{"server-port", "query.port"} {
+		if value, ok := s.GetProperty(key); ok {
+			if p, err := strconv.Atoi(value); err == nil {
+				port = p
+			}
+		}
+	}
+	return query.Full(ctx, net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+}
+
+// SyncPlayers replaces the tracked player list with the authoritative list
+// reported by a full stat query.
+func (s *Server) SyncPlayers(ctx context.Context) (*query.QueryResult, error) {
+	result, err := s.Query(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.players = append([]string{}, result.Players...)
+	s.mu.Unlock()
+	return result, nil
+}
@@ -0,0 +1,188 @@
+package gomcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+	"time"
+)
+
+// StopOptions configures the staged shutdown performed by StopWithOptions.
+//
+// Zero timeouts fall back to their defaults: 30s for saving, 30s for the stop
+// command and 10s for SIGTERM.
+type StopOptions struct {
+	Countdown        time.Duration // time to warn players before stopping, 0 disables the countdown
+	CountdownMessage string        // broadcast format, receives the remaining seconds via %d
+	SaveTimeout      time.Duration // how long to wait for "save-all flush" to complete
+	StopTimeout      time.Duration // how long to wait for the process to exit after "stop"
+	TermTimeout      time.Duration // how long to wait after SIGTERM before SIGKILL
+}
+
+const defaultCountdownMessage = "Server restarting in %d seconds"
+
+// saveMarkers are log fragments printed once the world has been flushed to disk.
+var saveMarkers = []string{
+	"Saved the game",
+	"All dimensions are saved",
+}
+
+func (o *StopOptions) withDefaults() StopOptions {
+	var out StopOptions
+	if o != nil {
+		out = *o
+	}
+	if out.CountdownMessage == "" {
+		out.CountdownMessage = defaultCountdownMessage
+	}
+	if out.SaveTimeout <= 0 {
+		out.SaveTimeout = 30 * time.Second
+	}
+	if out.StopTimeout <= 0 {
+		out.StopTimeout = 30 * time.Second
+	}
+	if out.TermTimeout <= 0 {
+		out.TermTimeout = 10 * time.Second
+	}
+	return out
+}
+
+// StopWithOptions shuts the server down in stages: an optional countdown broadcast,
+// "save-all flush" and "stop" over the console, then SIGTERM and finally SIGKILL.
+// Each stage only runs if the process is still alive when the previous one times out.
+func (s *Server) StopWithOptions(opts *StopOptions) error {
+	return s.StopContext(context.Background(), opts)
+}
+
+// StopContext is like StopWithOptions but stops waiting when ctx is done. The
+// shutdown stage that was in progress is not undone, so the process may still exit
+// afterwards.
+func (s *Server) StopContext(ctx context.Context, opts *StopOptions) error {
+	if s.cancelRestart() {
+		s.setState(StateStopped)
+		return nil
+	}
+	s.mu.Lock()
+	running, proc, done := s.running, s.process, s.done
+	if running && proc != nil {
+		s.stopRequested = true
+	}
+	s.mu.Unlock()
+	if !running {
+		return errors.New("server is not running")
+	}
+	if proc == nil {
+		return errors.New("server process is not available")
+	}
+
+	o := opts.withDefaults()
+	s.setState(StateStopping)
+
+	if s.canSendCommands() {
+		if o.Countdown > 0 {
+			if exited, err := s.countdown(ctx, o.Countdown, o.CountdownMessage, done); exited || err != nil {
+				return err
+			}
+		}
+		if exited, err := s.saveAll(ctx, o.SaveTimeout, done); exited || err != nil {
+			return err
+		}
+		if err := s.SendCommand("stop"); err == nil {
+			if exited, err := waitDone(ctx, done, o.StopTimeout); exited || err != nil {
+				return err
+			}
+		}
+	}
+
+	// SIGTERM cannot be delivered on Windows; go straight to killing the
+	// process when it fails.
+	if err := proc.Signal(syscall.SIGTERM); err == nil {
+		if exited, err := waitDone(ctx, done, o.TermTimeout); exited || err != nil {
+			return err
+		}
+	} else if exited, _ := waitDone(ctx, done, 0); exited {
+		return nil
+	}
+
+	if err := proc.Kill(); err != nil {
+		if exited, _ := waitDone(ctx, done, 0); exited {
+			return nil
+		}
+		return fmt.Errorf("failed to force kill server after timeout: %w", err)
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// countdown broadcasts the remaining time to players. It reports whether the
+// process exited in the meantime.
+func (s *Server) countdown(ctx context.Context, d time.Duration, format string, done <-chan struct{}) (bool, error) {
+	total := int((d + time.Second - 1) / time.Second)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for remaining := total; remaining > 0; remaining-- {
+		if remaining == total || remaining <= 5 || remaining == 10 || remaining%30 == 0 {
+			_ = s.SendCommand("say " + fmt.Sprintf(format, remaining))
+		}
+		select {
+		case <-done:
+			return true, nil
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return false, nil
+}
+
+// saveAll flushes the world to disk and waits for the server to confirm it. It
+// reports whether the process exited in the meantime.
+func (s *Server) saveAll(ctx context.Context, timeout time.Duration, done <-chan struct{}) (bool, error) {
+	saved, cancel := s.watchLog(saveMarkers...)
+	defer cancel()
+
+	if err := s.SendCommand("save-all flush"); err != nil {
+		return false, nil
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return true, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	case <-saved:
+	case <-timer.C:
+	}
+	return false, nil
+}
+
+// waitDone waits up to timeout for the process to exit and reports whether it did.
+// A zero timeout only polls.
+func waitDone(ctx context.Context, done <-chan struct{}, timeout time.Duration) (bool, error) {
+	if timeout <= 0 {
+		select {
+		case <-done:
+			return true, nil
+		default:
+			return false, nil
+		}
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return true, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	case <-timer.C:
+		return false, nil
+	}
+}
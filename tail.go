@@ -0,0 +1,77 @@
+package gomcserver
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"time"
+)
+
+const tailPollInterval = 250 * time.Millisecond
+
+// tailFile follows the file at path starting at offset, calling fn for every
+// complete line appended to it, until stop is closed. If the file is truncated or
+// replaced (as the server does when it rotates latest.log), reading restarts from
+// the beginning of the new file.
+func tailFile(path string, offset int64, stop <-chan struct{}, fn func(string)) {
+	var (
+		file    *os.File
+		info    os.FileInfo
+		pending []byte
+		buf     = make([]byte, 32*1024)
+	)
+	defer func() {
+		if file != nil {
+			_ = file.Close()
+		}
+	}()
+
+	ticker := time.NewTicker(tailPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if file == nil {
+			f, err := os.Open(path)
+			if err == nil {
+				if _, err := f.Seek(offset, io.SeekStart); err != nil {
+					offset = 0
+				}
+				file = f
+				info, _ = f.Stat()
+			}
+		}
+
+		if file != nil {
+			for {
+				n, err := file.Read(buf)
+				if n > 0 {
+					offset += int64(n)
+					pending = append(pending, buf[:n]...)
+					for {
+						i := bytes.IndexByte(pending, '\n')
+						if i < 0 {
+							break
+						}
+						fn(string(bytes.TrimRight(pending[:i], "\r")))
+						pending = pending[i+1:]
+					}
+				}
+				if err != nil {
+					break
+				}
+			}
+
+			if current, err := os.Stat(path); err == nil && (current.Size() < offset || (info != nil && !os.SameFile(info, current))) {
+				_ = file.Close()
+				file, offset, pending = nil, 0, nil
+				continue
+			}
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
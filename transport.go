@@ -0,0 +1,163 @@
+package gomcserver
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"github.com/xDefyingGravity/gomcserver/rcon"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// CommandTransport selects how commands are delivered to the server.
+type CommandTransport int
+
+const (
+	// TransportStdin writes commands to the server console.
+	TransportStdin CommandTransport = iota
+	// TransportRcon sends commands over RCON, enabling it when the server starts.
+	TransportRcon
+)
+
+const defaultRconPort = 25575
+
+// configureRcon enables RCON in server.properties, generating a password unless
+// one is already configured.
+func (s *Server) configureRcon(opts *StartOptions) error {
+	port := defaultRconPort
+	if opts.RconPort != nil {
+		port = *opts.RconPort
+	} else if value, ok := s.GetProperty("rcon.port"); ok {
+		if p, err := strconv.Atoi(value); err == nil {
+			port = p
+		}
+	}
+
+	password := ""
+	if opts.RconPassword != nil {
+		password = *opts.RconPassword
+	} else if value, ok := s.GetProperty("rcon.password"); ok {
+		password = value
+	}
+	if password == "" {
+		generated, err := generatePassword()
+		if err != nil {
+			return err
+		}
+		password = generated
+	}
+
+	s.SetProperty("enable-rcon", "true")
+	s.SetProperty("rcon.port", strconv.Itoa(port))
+	s.SetProperty("rcon.password", password)
+
+	s.mu.Lock()
+	s.rconAddr = net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+	s.rconPassword = password
+	s.mu.Unlock()
+	return nil
+}
+
+// RCON returns a connected RCON client for the server, dialling it on first use.
+// RCON must have been enabled through StartOptions.
+func (s *Server) RCON(ctx context.Context) (*rcon.Client, error) {
+	s.rconMu.Lock()
+	defer s.rconMu.Unlock()
+
+	if s.rconClient != nil {
+		return s.rconClient, nil
+	}
+	s.mu.Lock()
+	addr, password := s.rconAddr, s.rconPassword
+	s.mu.Unlock()
+	if addr == "" {
+		return nil, errors.New("rcon is not enabled")
+	}
+	client, err := rcon.Dial(ctx, addr, password)
+	if err != nil {
+		return nil, err
+	}
+	s.rconClient = client
+	return client, nil
+}
+
+// closeRcon drops the cached RCON connection, if any.
+func (s *Server) closeRcon() {
+	s.rconMu.Lock()
+	defer s.rconMu.Unlock()
+	if s.rconClient != nil {
+		_ = s.rconClient.Close()
+		s.rconClient = nil
+	}
+}
+
+// execRcon runs a command over RCON, reconnecting once if the cached connection
+// has gone stale.
+func (s *Server) execRcon(ctx context.Context, command string) (string, error) {
+	var lastErr error
+	for attempt := 0; attempt < 2; attempt++ {
+		client, err := s.RCON(ctx)
+		if err != nil {
+			return "", err
+		}
+		response, err := client.ExecuteContext(ctx, command)
+		if err == nil {
+			return response, nil
+		}
+		s.closeRcon()
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		lastErr = err
+	}
+	return "", lastErr
+}
+
+// writeCommand delivers a command over the configured transport.
+func (s *Server) writeCommand(command string) error {
+	s.mu.Lock()
+	transport, stdin := s.transport, s.stdinPipe
+	s.mu.Unlock()
+
+	if transport == TransportRcon {
+		_, err := s.execRcon(context.Background(), command)
+		return err
+	}
+	if stdin == nil {
+		return errors.New("stdin pipe is not available")
+	}
+	// Serialise writes so concurrent commands are not interleaved on the console.
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	_, err := io.WriteString(stdin, command+"\n")
+	return err
+}
+
+// canSendCommands reports whether a command transport is available.
+func (s *Server) canSendCommands() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.transport == TransportRcon || s.stdinPipe != nil
+}
+
+// rconResult wraps an RCON response as a CommandResult.
+func rconResult(command, response string) CommandResult {
+	result := CommandResult{Command: command}
+	for _, line := range strings.Split(response, "\n") {
+		if line = strings.TrimRight(line, "\r"); line != "" {
+			result.Entries = append(result.Entries, LogEntry{Message: line, Raw: line})
+		}
+	}
+	return result
+}
+
+func generatePassword() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -43,5 +43,9 @@ type VersionData struct {
 			URL  string `json:"url"`
 		} `json:"server_mappings"`
 	} `json:"downloads"`
-	ID string `json:"id"`
+	ID          string `json:"id"`
+	JavaVersion struct {
+		Component    string `json:"component"`
+		MajorVersion int    `json:"majorVersion"`
+	} `json:"javaVersion"`
 }
@@ -0,0 +1,57 @@
+package gomcserver
+
+import "strings"
+
+// logWaiter is notified the first time a stdout message contains one of its markers.
+type logWaiter struct {
+	markers []string
+	ch      chan string
+}
+
+// watchLog registers a waiter for the given markers. The returned channel receives
+// the matching message once; the returned function unregisters the waiter.
+func (s *Server) watchLog(markers ...string) (<-chan string, func()) {
+	w := &logWaiter{markers: markers, ch: make(chan string, 1)}
+
+	s.watchMu.Lock()
+	s.logWaiters = append(s.logWaiters, w)
+	s.watchMu.Unlock()
+
+	return w.ch, func() { s.removeLogWaiter(w) }
+}
+
+func (s *Server) removeLogWaiter(w *logWaiter) {
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+	for i, existing := range s.logWaiters {
+		if existing == w {
+			s.logWaiters = append(s.logWaiters[:i], s.logWaiters[i+1:]...)
+			return
+		}
+	}
+}
+
+// notifyLogWaiters hands the message to every waiter whose markers it contains.
+func (s *Server) notifyLogWaiters(message string) {
+	s.watchMu.Lock()
+	defer s.watchMu.Unlock()
+
+	remaining := s.logWaiters[:0]
+	for _, w := range s.logWaiters {
+		if containsAny(message, w.markers) {
+			w.ch <- message
+			continue
+		}
+		remaining = append(remaining, w)
+	}
+	s.logWaiters = remaining
+}
+
+func containsAny(s string, substrs []string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}